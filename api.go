@@ -0,0 +1,40 @@
+package gotoon
+
+import (
+	"context"
+	"time"
+)
+
+// DevicesAPI retrieves the Toon devices accessible to the user.
+type DevicesAPI interface {
+	// GetAgreements gets identifier information of accessible Toon devices.
+	GetAgreements() ([]Agreement, error)
+}
+
+// ThermostatAPI retrieves the status of the thermostat, and the current power and
+// gas usage, of Toon devices.
+type ThermostatAPI interface {
+	// GetStatus gets the status of the Toon device of the agreement.
+	GetStatus(agreement Agreement) (Status, error)
+	// GetStatusAll gets the status of all accessible Toon devices.
+	GetStatusAll(ctx context.Context) (map[string]StatusResult, error)
+}
+
+// ConsumptionAPI retrieves the consumption history of Toon devices.
+type ConsumptionAPI interface {
+	// GetGasFlow gets the gas consumption of the agreement between the given times.
+	GetGasFlow(agreement Agreement, fromTime, toTime time.Time) (FlowData, error)
+}
+
+// ToonAPI is the interface of the Toon client, implemented by *Toon.  Code using
+// the Toon API can depend on it, so that the client is replaced by a mock in its
+// tests, e.g. gotoontest.Mock.
+type ToonAPI interface {
+	DevicesAPI
+	ThermostatAPI
+	ConsumptionAPI
+	// TokenInfo returns the information of the current session.
+	TokenInfo() TokenInfo
+}
+
+var _ ToonAPI = (*Toon)(nil)
@@ -0,0 +1,130 @@
+package gotoon
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint names of the Toon API, used e.g. to configure the Cache TTLs and by
+// the Interceptors.
+const (
+	// EndpointAgreements is the endpoint listing the agreements, see Toon.GetAgreements.
+	EndpointAgreements = "agreements"
+	// EndpointStatus is the endpoint of the device status, see Toon.GetStatus.
+	EndpointStatus = "status"
+	// EndpointGasFlows is the endpoint of the gas consumption, see Toon.GetGasFlow.
+	EndpointGasFlows = "consumption/gas/flows"
+	// EndpointAuthorize is the endpoint authorising the user with the username and password.
+	EndpointAuthorize = "authorize/legacy"
+	// EndpointToken is the endpoint issuing and refreshing the access token.
+	EndpointToken = "token"
+)
+
+// Cache keeps the responses of the Toon API for a configurable time-to-live per
+// endpoint.  Cached responses of an agreement are invalidated after every write
+// to the agreement.
+//
+// A Cache may be used concurrently, but must not be shared between Toon clients
+// of different accounts.
+type Cache struct {
+	// TTL is the time-to-live of the responses by endpoint name, e.g. EndpointStatus.
+	// Responses of endpoints without a TTL are not cached.
+	TTL map[string]time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	agreementID string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// NewCache returns a Cache with the given time-to-live by endpoint name, e.g.
+//
+//	gotoon.NewCache(map[string]time.Duration{
+//	    gotoon.EndpointAgreements: time.Hour,
+//	    gotoon.EndpointStatus:     30 * time.Second,
+//	})
+func NewCache(ttl map[string]time.Duration) *Cache {
+	return &Cache{TTL: ttl}
+}
+
+// Invalidate removes the cached responses of the given agreement.
+func (c *Cache) Invalidate(agreementID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, e := range c.entries {
+		if e.agreementID == agreementID {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// Purge removes all cached responses.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+}
+
+// cacheKey returns the key of the response of the endpoint for the agreement,
+// with the given encoded query.
+func cacheKey(agreementID, endpoint, query string) string {
+	return strings.Join([]string{agreementID, endpoint, query}, "\x00")
+}
+
+// get returns the response cached under the key k, if it is not expired.
+func (c *Cache) get(k string) (body []byte, ok bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[k]
+	if !ok {
+		return
+	}
+	if !time.Now().Before(e.expiresAt) {
+		delete(c.entries, k)
+		return nil, false
+	}
+	return e.body, true
+}
+
+// put caches the response body of the endpoint for the agreement under the key k.
+func (c *Cache) put(k, agreementID, endpoint string, body []byte) {
+	if c == nil {
+		return
+	}
+
+	ttl := c.TTL[endpoint]
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+	c.entries[k] = cacheEntry{
+		agreementID: agreementID,
+		body:        body,
+		expiresAt:   time.Now().Add(ttl),
+	}
+}
+
+// invalidate is Invalidate allowing a nil Cache.
+func (c *Cache) invalidate(agreementID string) {
+	if c != nil {
+		c.Invalidate(agreementID)
+	}
+}
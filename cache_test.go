@@ -0,0 +1,44 @@
+package gotoon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+
+	c := NewCache(map[string]time.Duration{
+		EndpointAgreements: time.Hour,
+		EndpointStatus:     time.Hour,
+	})
+
+	kAgreements := cacheKey("", EndpointAgreements, "")
+	kStatus := cacheKey("1234", EndpointStatus, "")
+	kFlows := cacheKey("1234", EndpointGasFlows, "")
+
+	c.put(kAgreements, "", EndpointAgreements, []byte("agreements"))
+	c.put(kStatus, "1234", EndpointStatus, []byte("status"))
+	c.put(kFlows, "1234", EndpointGasFlows, []byte("flows"))
+
+	if b, ok := c.get(kStatus); !ok || string(b) != "status" {
+		t.Errorf("status not cached")
+	}
+	if _, ok := c.get(kFlows); ok {
+		t.Errorf("flows cached without TTL")
+	}
+
+	c.Invalidate("1234")
+	if _, ok := c.get(kStatus); ok {
+		t.Errorf("status not invalidated")
+	}
+	if _, ok := c.get(kAgreements); !ok {
+		t.Errorf("agreements invalidated with status")
+	}
+
+	c.TTL[EndpointStatus] = time.Nanosecond
+	c.put(kStatus, "1234", EndpointStatus, []byte("status"))
+	time.Sleep(time.Millisecond)
+	if _, ok := c.get(kStatus); ok {
+		t.Errorf("expired status returned")
+	}
+}
@@ -0,0 +1,297 @@
+package gotoon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the profiles
+// of a Config.
+const EnvPrefix = "TOONAPI"
+
+// Config holds named profiles, each describing a Toon client of an account.  It
+// is typically loaded from a YAML file with LoadConfig, e.g.
+//
+//	default: home
+//	profiles:
+//	  home:
+//	    tenantID: eneco
+//	    username: myEnecoUsername
+//	    consumerKey: ToonAPIConsumerKey
+//	    timeout: 30s
+//	    tokenFile: /var/lib/toon/home-token.json
+//	    cacheTTL:
+//	      agreements: 1h
+//	      status: 30s
+//	  office:
+//	    tenantID: eneco
+//	    username: myOfficeUsername
+//	    consumerKey: ToonAPIConsumerKey
+//	    credentialCommand: [toon-credentials, office]
+//
+// The secrets are not part of the config; they are provided by the credential
+// helper command of the profile, or else by environment variables, see Config.Toon.
+type Config struct {
+	// Default is the name of the profile used when no profile is given.
+	Default string `json:"default"`
+	// Profiles are the profiles by name.
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// Profile holds the configuration of a Toon client.
+type Profile struct {
+	TenantID    string    `json:"tenantID"`
+	Username    string    `json:"username"`
+	ConsumerKey string    `json:"consumerKey"`
+	Endpoints   Endpoints `json:"endpoints"`
+	// Timeout is the time limit of a HTTP request, e.g. "30s".
+	Timeout time.Duration `json:"timeout"`
+	// TimeZone is the time zone in which times are presented, e.g. "Europe/Amsterdam".
+	TimeZone string `json:"timeZone"`
+	// TokenFile is the file in which the access token is kept between sessions.
+	TokenFile string `json:"tokenFile"`
+	// CacheTTL is the time-to-live of cached responses by endpoint name, e.g. "status".
+	CacheTTL map[string]time.Duration `json:"cacheTTL"`
+	// RateLimit is the client-side rate limit of the calls.
+	RateLimit *RateLimit `json:"rateLimit"`
+	// CredentialCommand is a helper command providing the secrets at login, see
+	// CommandCredentials.
+	CredentialCommand []string `json:"credentialCommand"`
+	// MaxAttempts enables retrying calls failed with transient errors, using the
+	// DefaultRetryPolicy with the given maximum number of attempts.
+	MaxAttempts int `json:"maxAttempts"`
+}
+
+// UnmarshalJSON decodes the Profile from JSON, with the durations given as
+// strings such as "30s" or "1h".  LoadConfig decodes the YAML of a Config through
+// JSON, so that it applies to the config files as well.
+func (p *Profile) UnmarshalJSON(data []byte) (err error) {
+
+	type profile Profile
+	v := struct {
+		*profile
+		Timeout  duration            `json:"timeout"`
+		CacheTTL map[string]duration `json:"cacheTTL"`
+	}{profile: (*profile)(p)}
+
+	if err = json.Unmarshal(data, &v); err != nil {
+		return
+	}
+
+	p.Timeout = time.Duration(v.Timeout)
+	p.CacheTTL = nil
+	if v.CacheTTL != nil {
+		p.CacheTTL = make(map[string]time.Duration, len(v.CacheTTL))
+		for k, d := range v.CacheTTL {
+			p.CacheTTL[k] = time.Duration(d)
+		}
+	}
+	return
+}
+
+// duration is a time.Duration decoded from a string such as "30s".
+type duration time.Duration
+
+// UnmarshalJSON decodes the duration from a JSON string.
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("Cannot unmarshal value to duration: %s", string(data))
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// LoadConfig reads the Config from the YAML file at path.  The file is limited to
+// the subset of YAML of a Config: block mappings and sequences, flow sequences,
+// plain and quoted scalars, and comments.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err = unmarshalYAML(data, c); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// LoadToon creates the Toon of the given profile from the YAML config file at
+// path, with overrides from the environment.  If path is empty, the profile is
+// taken from the environment only.  See Config.Toon.
+func LoadToon(path, profile string) (*Toon, error) {
+	c := &Config{}
+	if path != "" {
+		var err error
+		if c, err = LoadConfig(path); err != nil {
+			return nil, err
+		}
+	}
+	return c.Toon(profile)
+}
+
+// Toon creates the Toon of the given profile, or of the default profile if
+// profile is empty.
+//
+// The settings of the profile are overridden by the environment variables
+// TOONAPI_<PROFILE>_<SETTING>, or else TOONAPI_<SETTING>, where <PROFILE> is the
+// profile name in upper case and <SETTING> is one of TENANT_ID, USERNAME and
+// CONSUMER_KEY.  The environment variable TOONAPI_PROFILE overrides the default
+// profile.  It is an error if the Config has profiles but none is given or
+// set as default.
+//
+// Unless the profile has a CredentialCommand, the secrets are provided at login by
+// the environment variables TOONAPI_<PROFILE>_<SECRET>, or else TOONAPI_<SECRET>,
+// where <SECRET> is PASSWORD or CONSUMER_SECRET, see EnvCredentials.
+func (c *Config) Toon(profile string) (*Toon, error) {
+
+	if profile == "" {
+		profile = os.Getenv(EnvPrefix + "_PROFILE")
+	}
+	if profile == "" {
+		profile = c.Default
+	}
+
+	p, ok := c.Profiles[profile]
+	if !ok && len(c.Profiles) > 0 {
+		if profile == "" {
+			return nil, fmt.Errorf("no profile given and no default profile")
+		}
+		return nil, fmt.Errorf("unknown profile: %s", profile)
+	}
+	p.overrideFromEnv(profile)
+
+	opts, err := p.options(profile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", profile, err)
+	}
+	return NewToon(opts...)
+}
+
+// overrideFromEnv overrides the settings of the Profile by the environment
+// variables of the profile with the given name.
+func (p *Profile) overrideFromEnv(name string) {
+
+	override := func(setting string, field *string) {
+		for _, prefix := range envPrefixes(name) {
+			if v, ok := os.LookupEnv(prefix + "_" + setting); ok {
+				*field = v
+				return
+			}
+		}
+	}
+
+	override("TENANT_ID", &p.TenantID)
+	override("USERNAME", &p.Username)
+	override("CONSUMER_KEY", &p.ConsumerKey)
+}
+
+// options returns the options to create the Toon of the Profile with the given
+// name with NewToon.
+func (p *Profile) options(name string) ([]Option, error) {
+
+	opts := []Option{
+		WithTenant(p.TenantID),
+		WithCredentials(p.Username, ""),
+		WithConsumer(p.ConsumerKey, ""),
+		WithEndpoints(p.Endpoints),
+	}
+
+	if len(p.CredentialCommand) > 0 {
+		opts = append(opts, WithCredentialProvider(CommandCredentials{
+			Command:     p.CredentialCommand,
+			TenantID:    p.TenantID,
+			ConsumerKey: p.ConsumerKey,
+		}))
+	} else {
+		var cp envCredentials
+		for _, prefix := range envPrefixes(name) {
+			cp = append(cp, EnvCredentials(prefix))
+		}
+		opts = append(opts, WithCredentialProvider(cp))
+	}
+	if p.Timeout != 0 {
+		opts = append(opts, WithTimeout(p.Timeout))
+	}
+	if p.TimeZone != "" {
+		loc, err := time.LoadLocation(p.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithLocation(loc))
+	}
+	if p.TokenFile != "" {
+		opts = append(opts, WithTokenStore(FileTokenStore(p.TokenFile)))
+	}
+	if len(p.CacheTTL) > 0 {
+		opts = append(opts, WithCache(NewCache(p.CacheTTL)))
+	}
+	if p.RateLimit != nil {
+		opts = append(opts, WithRateLimit(p.RateLimit))
+	}
+	if p.MaxAttempts > 0 {
+		rp := DefaultRetryPolicy()
+		rp.MaxAttempts = p.MaxAttempts
+		opts = append(opts, WithRetryPolicy(rp))
+	}
+
+	return opts, nil
+}
+
+// envCredentials provides the Credentials from the environment variables of the
+// first EnvCredentials setting them.
+type envCredentials []EnvCredentials
+
+// Credentials reads the Credentials from the environment.
+func (e envCredentials) Credentials(ctx context.Context) (c Credentials, err error) {
+	for i := len(e) - 1; i >= 0; i-- {
+		var ec Credentials
+		if ec, err = e[i].Credentials(ctx); err != nil {
+			return
+		}
+		if ec.Username != "" {
+			c.Username = ec.Username
+		}
+		if ec.Password != "" {
+			c.Password = ec.Password
+		}
+		if ec.ConsumerSecret != "" {
+			c.ConsumerSecret = ec.ConsumerSecret
+		}
+	}
+	return
+}
+
+// envPrefixes returns the prefixes of the environment variables of the profile
+// with the given name, the most specific first.
+func envPrefixes(name string) []string {
+	if name == "" {
+		return []string{EnvPrefix}
+	}
+	return []string{EnvPrefix + "_" + envName(name), EnvPrefix}
+}
+
+// envName converts the profile name into its form in environment variable names.
+func envName(profile string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, profile)
+}
@@ -0,0 +1,98 @@
+package gotoon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadToon(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "toon.yaml")
+	err := os.WriteFile(path, []byte(`
+# the accounts of the Toon tools
+default: home
+profiles:
+  home:
+    tenantID: eneco
+    username: home-user  # the Mijn Eneco account
+    consumerKey: key
+    timeout: 30s
+    cacheTTL:
+      agreements: 1h
+      status: 30s
+    rateLimit:
+      rate: 2
+      burst: 5
+  office-2:
+    tenantID: viesgo
+    username: "office-user"
+    consumerKey: key
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TOONAPI_PASSWORD", "password")
+	t.Setenv("TOONAPI_CONSUMER_SECRET", "secret")
+	t.Setenv("TOONAPI_OFFICE_2_USERNAME", "office-env-user")
+	t.Setenv("TOONAPI_OFFICE_2_PASSWORD", "office-password")
+
+	home, err := LoadToon(path, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if home.Username != "home-user" || home.Timeout != 30*time.Second || home.RateLimit == nil || home.RateLimit.Burst != 5 {
+		t.Errorf("unexpected home profile: %+v", home)
+	}
+	if home.Password != "" || home.ConsumerSecret != "" {
+		t.Errorf("secrets kept in the home profile")
+	}
+	if cred, err := home.credentials(context.Background()); err != nil || cred.Password != "password" || cred.ConsumerSecret != "secret" {
+		t.Errorf("unexpected home credentials: %+v, %+v", cred, err)
+	}
+	if home.Cache == nil || home.Cache.TTL[EndpointStatus] != 30*time.Second {
+		t.Errorf("cache TTLs not loaded")
+	}
+
+	office, err := LoadToon(path, "office-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if office.TenantID != "viesgo" || office.Username != "office-env-user" {
+		t.Errorf("unexpected office profile: %+v", office)
+	}
+	if cred, err := office.credentials(context.Background()); err != nil || cred.Password != "office-password" || cred.ConsumerSecret != "secret" {
+		t.Errorf("unexpected office credentials: %+v, %+v", cred, err)
+	}
+
+	if _, err = LoadToon(path, "unknown"); err == nil {
+		t.Errorf("unknown profile loaded")
+	}
+}
+
+func TestConfigNoDefault(t *testing.T) {
+
+	c := &Config{Profiles: map[string]Profile{"home": {TenantID: "eneco"}}}
+	if _, err := c.Toon(""); err == nil {
+		t.Errorf("profile loaded without a default profile")
+	}
+
+	// without profiles, the Toon is taken from the environment
+	t.Setenv("TOONAPI_TENANT_ID", "eneco")
+	t.Setenv("TOONAPI_CONSUMER_KEY", "key")
+	toon, err := (&Config{}).Toon("")
+	if err != nil || toon.TenantID != "eneco" {
+		t.Errorf("unexpected Toon from the environment: %+v, %+v", toon, err)
+	}
+}
+
+func TestConfigInvalidDuration(t *testing.T) {
+
+	c := &Config{}
+	if err := unmarshalYAML([]byte("profiles:\n  home:\n    timeout: 30\n"), c); err == nil {
+		t.Errorf("expect error on a numeric timeout")
+	}
+}
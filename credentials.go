@@ -0,0 +1,156 @@
+package gotoon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Credentials holds the secrets needed to authenticate with the Toon API.
+type Credentials struct {
+	// Username is the Tenant account name (e.g. the Mijn Eneco account)
+	Username string
+	// Password is the password of the Tenant account
+	Password string
+	// ConsumerSecret is the consumer secret of the Toon API
+	ConsumerSecret string
+}
+
+// CredentialProvider provides the Credentials when the Toon needs to login or
+// refresh the access token, so that the secrets need not to be kept in the Toon.
+//
+// Empty fields of the returned Credentials are taken from the corresponding fields
+// of the Toon.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// EnvCredentials provides the Credentials from the environment variables
+// <prefix>_USERNAME, <prefix>_PASSWORD and <prefix>_CONSUMER_SECRET.  The
+// prefix defaults to EnvPrefix.
+type EnvCredentials string
+
+// Credentials reads the Credentials from the environment.
+func (e EnvCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	prefix := string(e)
+	if prefix == "" {
+		prefix = EnvPrefix
+	}
+	return Credentials{
+		Username:       os.Getenv(prefix + "_USERNAME"),
+		Password:       os.Getenv(prefix + "_PASSWORD"),
+		ConsumerSecret: os.Getenv(prefix + "_CONSUMER_SECRET"),
+	}, nil
+}
+
+// FileCredentials provides the Credentials from files containing one secret each,
+// e.g. Docker or Kubernetes secrets.  Surrounding whitespace in the files is
+// ignored, and files with an empty path are not read.
+type FileCredentials struct {
+	UsernameFile       string
+	PasswordFile       string
+	ConsumerSecretFile string
+}
+
+// Credentials reads the Credentials from the files.
+func (f FileCredentials) Credentials(ctx context.Context) (c Credentials, err error) {
+	read := func(path string, field *string) {
+		if path == "" || err != nil {
+			return
+		}
+		var data []byte
+		if data, err = ioutil.ReadFile(path); err == nil {
+			*field = strings.TrimSpace(string(data))
+		}
+	}
+	read(f.UsernameFile, &c.Username)
+	read(f.PasswordFile, &c.Password)
+	read(f.ConsumerSecretFile, &c.ConsumerSecret)
+	return
+}
+
+// CommandCredentials provides the Credentials from an external helper command,
+// similar to the git credential helpers.  The command is given the tenant and the
+// consumer key on its standard input as
+//
+//	tenant_id=eneco
+//	consumer_key=ToonAPIConsumerKey
+//
+// and prints the Credentials on its standard output as
+//
+//	username=myEnecoUsername
+//	password=myEnecoPassword
+//	consumer_secret=ToonAPIConsumerSecret
+//
+// Lines with other keys are ignored.
+type CommandCredentials struct {
+	// Command is the helper command followed by its arguments.
+	Command []string
+	// TenantID and ConsumerKey are passed to the helper command.
+	TenantID    string
+	ConsumerKey string
+}
+
+// Credentials runs the helper command and parses its output.
+func (h CommandCredentials) Credentials(ctx context.Context) (c Credentials, err error) {
+
+	if len(h.Command) == 0 {
+		err = fmt.Errorf("missing credential helper command")
+		return
+	}
+
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("tenant_id=%s\nconsumer_key=%s\n\n", h.TenantID, h.ConsumerKey))
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		err = fmt.Errorf("credential helper %s failed: %w", h.Command[0], err)
+		return
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		k, v, ok := strings.Cut(s.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "username":
+			c.Username = v
+		case "password":
+			c.Password = v
+		case "consumer_secret":
+			c.ConsumerSecret = v
+		}
+	}
+	err = s.Err()
+	return
+}
+
+// credentials returns the Credentials from the CredentialProvider of the Toon,
+// completed by the corresponding fields of the Toon.
+func (t *Toon) credentials(ctx context.Context) (c Credentials, err error) {
+
+	if t.Credentials != nil {
+		if c, err = t.Credentials.Credentials(ctx); err != nil {
+			return
+		}
+	}
+
+	if c.Username == "" {
+		c.Username = t.Username
+	}
+	if c.Password == "" {
+		c.Password = t.Password
+	}
+	if c.ConsumerSecret == "" {
+		c.ConsumerSecret = t.ConsumerSecret
+	}
+	return
+}
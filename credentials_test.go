@@ -0,0 +1,33 @@
+package gotoon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialProviders(t *testing.T) {
+
+	ctx := context.Background()
+
+	t.Setenv("TOONTEST_USERNAME", "env-user")
+	t.Setenv("TOONTEST_PASSWORD", "env-password")
+	toon := Toon{ConsumerSecret: "secret", Credentials: EnvCredentials("TOONTEST")}
+	if c, err := toon.credentials(ctx); err != nil || c != (Credentials{"env-user", "env-password", "secret"}) {
+		t.Errorf("unexpected env credentials: %+v, %+v", c, err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "password"), []byte("file-password\n"), 0600)
+	toon = Toon{Username: "user", Credentials: FileCredentials{PasswordFile: filepath.Join(dir, "password")}}
+	if c, err := toon.credentials(ctx); err != nil || c != (Credentials{"user", "file-password", ""}) {
+		t.Errorf("unexpected file credentials: %+v, %+v", c, err)
+	}
+
+	helper := []string{"sh", "-c", `grep -q tenant_id=eneco && printf 'username=cmd-user\npassword=a=b\nconsumer_secret=cmd-secret\n'`}
+	toon = Toon{Credentials: CommandCredentials{Command: helper, TenantID: "eneco"}}
+	if c, err := toon.credentials(ctx); err != nil || c != (Credentials{"cmd-user", "a=b", "cmd-secret"}) {
+		t.Errorf("unexpected command credentials: %+v, %+v", c, err)
+	}
+}
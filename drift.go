@@ -0,0 +1,317 @@
+package gotoon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DriftKind is the kind of a SchemaDrift.
+type DriftKind int
+
+const (
+	// DriftUnknownField indicates a field in the response unknown to the model.
+	DriftUnknownField DriftKind = iota
+	// DriftMissingField indicates a field of the model missing in the response.
+	DriftMissingField
+	// DriftTypeMismatch indicates a field in the response with a JSON type not
+	// matching the type of the field in the model.
+	DriftTypeMismatch
+	// DriftInvalidValue indicates a field in the response with a value unknown to
+	// the enum type of the field in the model, e.g. a new ActiveState.
+	DriftInvalidValue
+)
+
+func (k DriftKind) String() string {
+	switch k {
+	case DriftUnknownField:
+		return "unknown field"
+	case DriftMissingField:
+		return "missing field"
+	case DriftTypeMismatch:
+		return "type mismatch"
+	case DriftInvalidValue:
+		return "invalid value"
+	default:
+		return "unknown drift"
+	}
+}
+
+// SchemaDrift is a difference between a response of the Toon API and the model
+// of the library.
+type SchemaDrift struct {
+	Kind DriftKind
+	// Path is the path of the field in the response, e.g. "gasUsage.dayCost" or
+	// "hours[].value" for the fields of the elements of an array.
+	Path string
+	// Expected is the expected JSON type, and Actual the one in the response, of a
+	// DriftTypeMismatch.  Actual is the value in the response of a DriftInvalidValue.
+	Expected string
+	Actual   string
+}
+
+func (d SchemaDrift) String() string {
+	switch d.Kind {
+	case DriftTypeMismatch:
+		return fmt.Sprintf("%s: %s, expected %s, got %s", d.Path, d.Kind, d.Expected, d.Actual)
+	case DriftInvalidValue:
+		return fmt.Sprintf("%s: %s %s", d.Path, d.Kind, d.Actual)
+	}
+	return fmt.Sprintf("%s: %s", d.Path, d.Kind)
+}
+
+// SchemaDriftError reports the differences between a response of an endpoint of
+// the Toon API and the model of the library.
+type SchemaDriftError struct {
+	// Endpoint is the name of the endpoint, e.g. EndpointStatus.
+	Endpoint string
+	// Drifts are the differences, ordered by path.
+	Drifts []SchemaDrift
+	// Err is the error decoding the response, if any.
+	Err error
+}
+
+func (e *SchemaDriftError) Error() string {
+	s := make([]string, len(e.Drifts))
+	for i, d := range e.Drifts {
+		s[i] = d.String()
+	}
+	return fmt.Sprintf("schema drift of %s: %s", e.Endpoint, strings.Join(s, "; "))
+}
+
+func (e *SchemaDriftError) Unwrap() error { return e.Err }
+
+// decode unmarshals the response data of the endpoint into v.  If the Toon has
+// StrictDecoding or OnSchemaDrift set, the response is checked against the model.
+func (t *Toon) decode(ctx context.Context, endpoint string, data []byte, v interface{}) (err error) {
+
+	err = json.Unmarshal(data, v)
+
+	if !t.StrictDecoding && t.OnSchemaDrift == nil {
+		return
+	}
+
+	drifts, cerr := checkSchema(data, reflect.TypeOf(v).Elem())
+	if cerr != nil || len(drifts) == 0 {
+		return
+	}
+
+	e := &SchemaDriftError{Endpoint: endpoint, Drifts: drifts, Err: err}
+	if t.OnSchemaDrift != nil {
+		t.OnSchemaDrift(e)
+	}
+	if t.StrictDecoding || err != nil {
+		err = e
+	}
+	return
+}
+
+var (
+	timeType      = reflect.TypeOf(Time{})
+	jsonBoolType  = reflect.TypeOf(jsonBool(false))
+	extraType     = reflect.TypeOf(Extra(nil))
+	validatorType = reflect.TypeOf((*validator)(nil)).Elem()
+)
+
+// validator is implemented by the enum types, e.g. ActiveState.
+type validator interface {
+	Valid() bool
+}
+
+// checkSchema compares the JSON data with the model type t.
+func checkSchema(data []byte, t reflect.Type) ([]SchemaDrift, error) {
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var raw interface{}
+	if err := d.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[SchemaDrift]bool)
+	var drifts []SchemaDrift
+	report := func(drift SchemaDrift) {
+		if !seen[drift] {
+			seen[drift] = true
+			drifts = append(drifts, drift)
+		}
+	}
+
+	walkSchema("", raw, t, report)
+
+	sort.SliceStable(drifts, func(i, j int) bool { return drifts[i].Path < drifts[j].Path })
+	return drifts, nil
+}
+
+// walkSchema compares the decoded JSON value raw at path with the type t, and
+// reports the differences.
+func walkSchema(path string, raw interface{}, t reflect.Type, report func(SchemaDrift)) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// null is accepted for every type, like encoding/json does
+	if raw == nil {
+		return
+	}
+
+	mismatch := func(expected string) {
+		report(SchemaDrift{Kind: DriftTypeMismatch, Path: path, Expected: expected, Actual: jsonType(raw)})
+	}
+
+	switch {
+	case t == timeType:
+		if !isInteger(raw) {
+			mismatch("integer")
+		}
+		return
+	case t == jsonBoolType:
+		if _, ok := raw.(bool); !ok && !isInteger(raw) {
+			mismatch("boolean")
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			mismatch("object")
+			return
+		}
+
+		known := make(map[string]bool, len(obj))
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Type == extraType {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			key, v, ok := lookupField(obj, name)
+			if !ok {
+				report(SchemaDrift{Kind: DriftMissingField, Path: joinPath(path, name)})
+				continue
+			}
+			known[key] = true
+			walkSchema(joinPath(path, name), v, f.Type, report)
+		}
+
+		for key := range obj {
+			if !known[key] {
+				report(SchemaDrift{Kind: DriftUnknownField, Path: joinPath(path, key)})
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		arr, ok := raw.([]interface{})
+		if !ok {
+			mismatch("array")
+			return
+		}
+		for _, v := range arr {
+			walkSchema(path+"[]", v, t.Elem(), report)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !isInteger(raw) {
+			mismatch("integer")
+			return
+		}
+		checkValue(path, raw, t, report)
+
+	case reflect.Float32, reflect.Float64:
+		if _, ok := raw.(json.Number); !ok {
+			mismatch("number")
+		}
+
+	case reflect.String:
+		if _, ok := raw.(string); !ok {
+			mismatch("string")
+			return
+		}
+		checkValue(path, raw, t, report)
+
+	case reflect.Bool:
+		if _, ok := raw.(bool); !ok {
+			mismatch("boolean")
+		}
+	}
+}
+
+// checkValue reports the decoded JSON value raw at path if it is unknown to the
+// enum type t.  Values of other types are not checked.
+func checkValue(path string, raw interface{}, t reflect.Type, report func(SchemaDrift)) {
+	if !t.Implements(validatorType) {
+		return
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return
+	}
+	v := reflect.New(t)
+	if json.Unmarshal(b, v.Interface()) == nil && !v.Elem().Interface().(validator).Valid() {
+		report(SchemaDrift{Kind: DriftInvalidValue, Path: path, Actual: string(b)})
+	}
+}
+
+// lookupField finds the field name in the JSON object obj, in the case-insensitive
+// way encoding/json does.
+func lookupField(obj map[string]interface{}, name string) (key string, v interface{}, ok bool) {
+	if v, ok = obj[name]; ok {
+		return name, v, true
+	}
+	for key, v = range obj {
+		if strings.EqualFold(key, name) {
+			return key, v, true
+		}
+	}
+	return "", nil, false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// isInteger reports whether the decoded JSON value raw is an integer number.
+func isInteger(raw interface{}) bool {
+	n, ok := raw.(json.Number)
+	return ok && !strings.ContainsAny(string(n), ".eE")
+}
+
+// jsonType returns the JSON type of the decoded JSON value raw.
+func jsonType(raw interface{}) string {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if isInteger(v) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,100 @@
+package gotoon
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaDrift(t *testing.T) {
+
+	data := []byte(`{"thermostatStates": {"state": [{"id": 0, "tempValue": 2000, "dhw": 1}, {"id": 1, "tempVal": 1800, "dhw": 1}]},
+		"thermostatInfo": {"currentSetpoint": 20.5},
+		"gasUsage": {"dayCost": "1.23", "isSmart": 1},
+		"powerUsage": {"isSmart": "yes"},
+		"newSection": {}}`)
+
+	drifts, err := checkSchema(data, reflect.TypeOf(Status{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := map[SchemaDrift]bool{
+		{Kind: DriftUnknownField, Path: "newSection"}:                                                            true,
+		{Kind: DriftMissingField, Path: "lastUpdateFromDisplay"}:                                                 true,
+		{Kind: DriftUnknownField, Path: "thermostatStates.state[].tempVal"}:                                      true,
+		{Kind: DriftMissingField, Path: "thermostatStates.state[].tempValue"}:                                    true,
+		{Kind: DriftTypeMismatch, Path: "thermostatInfo.currentSetpoint", Expected: "integer", Actual: "number"}: true,
+		{Kind: DriftTypeMismatch, Path: "gasUsage.dayCost", Expected: "number", Actual: "string"}:                true,
+		{Kind: DriftTypeMismatch, Path: "powerUsage.isSmart", Expected: "boolean", Actual: "string"}:             true,
+	}
+
+	found := make(map[SchemaDrift]bool)
+	for _, d := range drifts {
+		found[d] = true
+	}
+	for d := range expected {
+		if !found[d] {
+			t.Errorf("drift not reported: %s", d)
+		}
+	}
+	for _, d := range drifts {
+		if d.Path == "gasUsage.isSmart" {
+			t.Errorf("integer boolean reported as drift: %s", d)
+		}
+	}
+}
+
+func TestStrictDecoding(t *testing.T) {
+
+	var reported *SchemaDriftError
+	toon := Toon{OnSchemaDrift: func(e *SchemaDriftError) { reported = e }}
+
+	var agreements []Agreement
+	data := []byte(`[{"agreementId": "1234", "newField": 1}]`)
+	if err := toon.decode(context.Background(), EndpointAgreements, data, &agreements); err != nil {
+		t.Fatalf("drift fails non-strict decoding: %+v", err)
+	}
+	if reported == nil || reported.Endpoint != EndpointAgreements || agreements[0].AgreementID != "1234" {
+		t.Fatalf("drift not reported: %+v", reported)
+	}
+
+	toon.StrictDecoding = true
+	err := toon.decode(context.Background(), EndpointAgreements, data, &agreements)
+	var e *SchemaDriftError
+	if !errors.As(err, &e) {
+		t.Errorf("expected SchemaDriftError, got %+v", err)
+	}
+}
+
+func TestSchemaDriftInvalidEnum(t *testing.T) {
+
+	toon := Toon{}
+
+	var info ThermostatInfo
+	data := []byte(`{"activeState": 7, "programState": 1}`)
+	if err := toon.decode(context.Background(), EndpointStatus, data, &info); err != nil || info.ActiveState != 7 {
+		t.Fatalf("unknown value fails decoding: %+v", err)
+	}
+
+	toon.StrictDecoding = true
+	err := toon.decode(context.Background(), EndpointStatus, data, &info)
+	var e *SchemaDriftError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected SchemaDriftError, got %+v", err)
+	}
+
+	found := false
+	for _, d := range e.Drifts {
+		if d.Kind == DriftInvalidValue {
+			found = true
+			if d.Path != "activeState" || d.String() != "activeState: invalid value 7" {
+				t.Errorf("unexpected drift: %s", d)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("invalid value not reported: %+v", e.Drifts)
+	}
+}
@@ -0,0 +1,134 @@
+package gotoon
+
+import (
+	"fmt"
+)
+
+// ActiveState is a temperature preset of the thermostat, used by ThermostatInfo.ActiveState
+// and NextState, and ThermostatState.ID.
+//
+// Decoding doesn't check the value, so that a preset new to the library can still be
+// used; an unknown value is reported as a SchemaDrift with StrictDecoding or
+// OnSchemaDrift of the Toon.  See Valid.
+type ActiveState int
+
+const (
+	// StateManual indicates no preset is active, i.e. the setpoint is set manually.
+	StateManual ActiveState = -1
+	// StateComfort is the comfort preset.
+	StateComfort ActiveState = 0
+	// StateHome is the home preset.
+	StateHome ActiveState = 1
+	// StateSleep is the sleep preset.
+	StateSleep ActiveState = 2
+	// StateAway is the away preset.
+	StateAway ActiveState = 3
+	// StateHoliday is the preset of the holiday mode.
+	StateHoliday ActiveState = 4
+)
+
+var activeStateNames = map[ActiveState]string{
+	StateManual:  "manual",
+	StateComfort: "comfort",
+	StateHome:    "home",
+	StateSleep:   "sleep",
+	StateAway:    "away",
+	StateHoliday: "holiday",
+}
+
+func (s ActiveState) String() string {
+	if name, ok := activeStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("ActiveState(%d)", int(s))
+}
+
+// Valid reports whether s is a known ActiveState.
+func (s ActiveState) Valid() bool {
+	_, ok := activeStateNames[s]
+	return ok
+}
+
+// ParseActiveState returns the ActiveState of the given name, e.g. "comfort".
+func ParseActiveState(name string) (ActiveState, error) {
+	for s, n := range activeStateNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown active state: %s", name)
+}
+
+// ProgramState is the state of the weekly program of the thermostat, used by
+// ThermostatInfo.ProgramState and NextProgram.
+//
+// Like ActiveState, decoding doesn't check the value.
+type ProgramState int
+
+const (
+	// ProgramOff indicates the program is off; the setpoint is set manually.
+	ProgramOff ProgramState = 0
+	// ProgramOn indicates the thermostat follows the program.
+	ProgramOn ProgramState = 1
+	// ProgramOverride indicates the program is temporarily overridden until the
+	// next program change.
+	ProgramOverride ProgramState = 2
+	// ProgramHoliday indicates the holiday mode is active.
+	ProgramHoliday ProgramState = 4
+)
+
+var programStateNames = map[ProgramState]string{
+	ProgramOff:      "off",
+	ProgramOn:       "on",
+	ProgramOverride: "override",
+	ProgramHoliday:  "holiday",
+}
+
+func (s ProgramState) String() string {
+	if name, ok := programStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("ProgramState(%d)", int(s))
+}
+
+// Valid reports whether s is a known ProgramState.
+func (s ProgramState) Valid() bool {
+	_, ok := programStateNames[s]
+	return ok
+}
+
+// ParseProgramState returns the ProgramState of the given name, e.g. "on".
+func ParseProgramState(name string) (ProgramState, error) {
+	for s, n := range programStateNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown program state: %s", name)
+}
+
+// HeatingType is the type of heating of the Toon device, see Agreement.HeatingType.
+//
+// Like ActiveState, decoding doesn't check the value.
+type HeatingType string
+
+const (
+	// HeatingGas is heating by a gas boiler.
+	HeatingGas HeatingType = "GAS"
+	// HeatingDistrict is district heating.
+	HeatingDistrict HeatingType = "DISTRICT_HEATING"
+	// HeatingElectric is electric heating, e.g. by a heat pump.
+	HeatingElectric HeatingType = "ELECTRIC"
+)
+
+func (h HeatingType) String() string { return string(h) }
+
+// Valid reports whether h is a known HeatingType.
+func (h HeatingType) Valid() bool {
+	switch h {
+	case HeatingGas, HeatingDistrict, HeatingElectric:
+		return true
+	default:
+		return false
+	}
+}
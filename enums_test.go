@@ -0,0 +1,48 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEnumsJSON(t *testing.T) {
+
+	in := `{"programState":2,"activeState":-1,"nextProgram":1,"nextState":3}`
+
+	var info ThermostatInfo
+	if err := json.Unmarshal([]byte(in), &info); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if info.ProgramState != ProgramOverride || info.ActiveState != StateManual ||
+		info.NextProgram != ProgramOn || info.NextState != StateAway {
+		t.Errorf("unexpected states: %+v", info)
+	}
+	if s := info.ActiveState.String() + "," + info.ProgramState.String(); s != "manual,override" {
+		t.Errorf("unexpected names: %s", s)
+	}
+
+	out, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	var back ThermostatInfo
+	json.Unmarshal(out, &back)
+	if back.ProgramState != info.ProgramState || back.NextState != info.NextState {
+		t.Errorf("states not round-tripped: %s", out)
+	}
+
+	// unknown values are kept, but reported invalid
+	var a Agreement
+	json.Unmarshal([]byte(`{"heatingType": "HEAT_PUMP"}`), &a)
+	if a.HeatingType != "HEAT_PUMP" || a.HeatingType.Valid() {
+		t.Errorf("unexpected heating type: %v", a.HeatingType)
+	}
+	if s := ActiveState(7); s.Valid() || s.String() != "ActiveState(7)" {
+		t.Errorf("unexpected unknown state: %v", s)
+	}
+
+	if s, err := ParseActiveState("sleep"); err != nil || s != StateSleep {
+		t.Errorf("unexpected parsed state: %v, %+v", s, err)
+	}
+}
@@ -0,0 +1,224 @@
+package gotoon
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Extra holds the fields of a JSON object that are unknown to the model struct,
+// so that fields newly added by the Toon API can be used before the library
+// supports them.  The fields are written back when the struct is marshalled.
+type Extra map[string]json.RawMessage
+
+// knownFields caches the JSON field names of the model structs by type.
+var knownFields sync.Map
+
+// fieldNames returns the JSON field names of the struct type t.
+func fieldNames(t reflect.Type) []string {
+	if names, ok := knownFields.Load(t); ok {
+		return names.([]string)
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+
+	knownFields.Store(t, names)
+	return names
+}
+
+// isKnown reports whether key matches one of the names, in the case-insensitive
+// way encoding/json matches keys to fields.
+func isKnown(key string, names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalExtra unmarshals the JSON object data into the struct pointed by v,
+// and the fields unknown to the struct into extra.
+func unmarshalExtra(data []byte, v interface{}, extra *Extra) error {
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	names := fieldNames(reflect.TypeOf(v).Elem())
+	for key := range fields {
+		if isKnown(key, names) {
+			delete(fields, key)
+		}
+	}
+
+	*extra = nil
+	if len(fields) > 0 {
+		*extra = fields
+	}
+	return nil
+}
+
+// marshalExtra marshals the struct v into a JSON object, followed by the fields
+// in extra that are unknown to the struct, in alphabetical order.
+func marshalExtra(v interface{}, extra Extra) ([]byte, error) {
+
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	names := fieldNames(reflect.TypeOf(v))
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		if !isKnown(key, names) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for i, key := range keys {
+		if i > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(extra[key])
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON unmarshals the Agreement, keeping unknown fields in Extra.
+func (a *Agreement) UnmarshalJSON(data []byte) error {
+	type plain Agreement
+	return unmarshalExtra(data, (*plain)(a), &a.Extra)
+}
+
+// MarshalJSON marshals the Agreement, including the unknown fields in Extra.
+func (a Agreement) MarshalJSON() ([]byte, error) {
+	type plain Agreement
+	return marshalExtra(plain(a), a.Extra)
+}
+
+// UnmarshalJSON unmarshals the ThermostatStates, keeping unknown fields in Extra.
+func (s *ThermostatStates) UnmarshalJSON(data []byte) error {
+	type plain ThermostatStates
+	return unmarshalExtra(data, (*plain)(s), &s.Extra)
+}
+
+// MarshalJSON marshals the ThermostatStates, including the unknown fields in Extra.
+func (s ThermostatStates) MarshalJSON() ([]byte, error) {
+	type plain ThermostatStates
+	return marshalExtra(plain(s), s.Extra)
+}
+
+// UnmarshalJSON unmarshals the ThermostatState, keeping unknown fields in Extra.
+func (s *ThermostatState) UnmarshalJSON(data []byte) error {
+	type plain ThermostatState
+	return unmarshalExtra(data, (*plain)(s), &s.Extra)
+}
+
+// MarshalJSON marshals the ThermostatState, including the unknown fields in Extra.
+func (s ThermostatState) MarshalJSON() ([]byte, error) {
+	type plain ThermostatState
+	return marshalExtra(plain(s), s.Extra)
+}
+
+// UnmarshalJSON unmarshals the ThermostatInfo, keeping unknown fields in Extra.
+func (i *ThermostatInfo) UnmarshalJSON(data []byte) error {
+	type plain ThermostatInfo
+	return unmarshalExtra(data, (*plain)(i), &i.Extra)
+}
+
+// MarshalJSON marshals the ThermostatInfo, including the unknown fields in Extra.
+func (i ThermostatInfo) MarshalJSON() ([]byte, error) {
+	type plain ThermostatInfo
+	return marshalExtra(plain(i), i.Extra)
+}
+
+// UnmarshalJSON unmarshals the PowerUsage, keeping unknown fields in Extra.
+func (u *PowerUsage) UnmarshalJSON(data []byte) error {
+	type plain PowerUsage
+	return unmarshalExtra(data, (*plain)(u), &u.Extra)
+}
+
+// MarshalJSON marshals the PowerUsage, including the unknown fields in Extra.
+func (u PowerUsage) MarshalJSON() ([]byte, error) {
+	type plain PowerUsage
+	return marshalExtra(plain(u), u.Extra)
+}
+
+// UnmarshalJSON unmarshals the GasUsage, keeping unknown fields in Extra.
+func (u *GasUsage) UnmarshalJSON(data []byte) error {
+	type plain GasUsage
+	return unmarshalExtra(data, (*plain)(u), &u.Extra)
+}
+
+// MarshalJSON marshals the GasUsage, including the unknown fields in Extra.
+func (u GasUsage) MarshalJSON() ([]byte, error) {
+	type plain GasUsage
+	return marshalExtra(plain(u), u.Extra)
+}
+
+// UnmarshalJSON unmarshals the Status, keeping unknown fields in Extra.
+func (s *Status) UnmarshalJSON(data []byte) error {
+	type plain Status
+	return unmarshalExtra(data, (*plain)(s), &s.Extra)
+}
+
+// MarshalJSON marshals the Status, including the unknown fields in Extra.
+func (s Status) MarshalJSON() ([]byte, error) {
+	type plain Status
+	return marshalExtra(plain(s), s.Extra)
+}
+
+// UnmarshalJSON unmarshals the FlowDataPoint, keeping unknown fields in Extra.
+func (p *FlowDataPoint) UnmarshalJSON(data []byte) error {
+	type plain FlowDataPoint
+	return unmarshalExtra(data, (*plain)(p), &p.Extra)
+}
+
+// MarshalJSON marshals the FlowDataPoint, including the unknown fields in Extra.
+func (p FlowDataPoint) MarshalJSON() ([]byte, error) {
+	type plain FlowDataPoint
+	return marshalExtra(plain(p), p.Extra)
+}
+
+// UnmarshalJSON unmarshals the FlowData, keeping unknown fields in Extra.
+func (f *FlowData) UnmarshalJSON(data []byte) error {
+	type plain FlowData
+	return unmarshalExtra(data, (*plain)(f), &f.Extra)
+}
+
+// MarshalJSON marshals the FlowData, including the unknown fields in Extra.
+func (f FlowData) MarshalJSON() ([]byte, error) {
+	type plain FlowData
+	return marshalExtra(plain(f), f.Extra)
+}
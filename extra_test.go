@@ -0,0 +1,63 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtraRoundTrip(t *testing.T) {
+
+	in := `{"agreementId":"1234","heatingType":"GAS","isToonly":false,"newFeature":{"enabled":true},"score":42}`
+
+	var a Agreement
+	if err := json.Unmarshal([]byte(in), &a); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if len(a.Extra) != 2 || string(a.Extra["newFeature"]) != `{"enabled":true}` || string(a.Extra["score"]) != "42" {
+		t.Errorf("unexpected extra fields: %+v", a.Extra)
+	}
+	if a.AgreementID != "1234" || a.HeatingType != HeatingGas {
+		t.Errorf("known fields not decoded: %+v", a)
+	}
+
+	out, err := json.Marshal(a)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	var back map[string]json.RawMessage
+	if err = json.Unmarshal(out, &back); err != nil {
+		t.Fatalf("invalid JSON %s: %+v", out, err)
+	}
+	if string(back["newFeature"]) != `{"enabled":true}` || string(back["agreementId"]) != `"1234"` {
+		t.Errorf("extra fields not written back: %s", out)
+	}
+}
+
+func TestExtraNested(t *testing.T) {
+
+	in := `{"thermostatInfo":{"currentSetpoint":2000,"ecoMode":1},"gasUsage":{"isSmart":1,"heatPump":null},"newSection":[1,2]}`
+
+	var s Status
+	if err := json.Unmarshal([]byte(in), &s); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if string(s.ThermostatInfo.Extra["ecoMode"]) != "1" ||
+		string(s.GasUsage.Extra["heatPump"]) != "null" ||
+		string(s.Extra["newSection"]) != "[1,2]" {
+		t.Errorf("unexpected extra fields: %+v, %+v, %+v", s.Extra, s.ThermostatInfo.Extra, s.GasUsage.Extra)
+	}
+	if s.PowerUsage.Extra != nil {
+		t.Errorf("extra fields of absent object: %+v", s.PowerUsage.Extra)
+	}
+
+	out, _ := json.Marshal(s)
+	var back Status
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatalf("invalid JSON %s: %+v", out, err)
+	}
+	if string(back.ThermostatInfo.Extra["ecoMode"]) != "1" || back.ThermostatInfo.CurrentSetPoint != 2000 {
+		t.Errorf("nested extra fields not round-tripped: %s", out)
+	}
+}
@@ -0,0 +1,356 @@
+package gotoon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// addPayloads adds the API payloads in testdata matching the pattern to the seed
+// corpus of the fuzz target.
+func addPayloads(f *testing.F, pattern string) {
+	files, err := filepath.Glob(filepath.Join("testdata", pattern))
+	if err != nil || len(files) == 0 {
+		f.Fatalf("no payloads %s: %v", pattern, err)
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+}
+
+// checkRoundTrip checks that a decoded value v re-encodes into JSON which decodes
+// into a value of the same encoding.
+func checkRoundTrip[T any](t *testing.T, v *T) {
+	b1, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("fail marshalling %+v: %v", v, err)
+	}
+	var w T
+	if err := json.Unmarshal(b1, &w); err != nil {
+		t.Fatalf("fail unmarshalling %s: %v", b1, err)
+	}
+	b2, err := json.Marshal(&w)
+	if err != nil {
+		t.Fatalf("fail marshalling %+v: %v", w, err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Errorf("round trip changed encoding:\n%s\n%s", b1, b2)
+	}
+}
+
+// decodeNumbers decodes the JSON data keeping the numbers as they are.
+func decodeNumbers(data []byte) (v interface{}, err error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	err = d.Decode(&v)
+	return
+}
+
+// checkValues checks that the value v decoded from the payload re-encodes into the
+// values of the payload, comparing the values rather than their encoding, so that
+// a lossy decoding of a field is reported even if it is stable.
+func checkValues(t *testing.T, name string, payload []byte, v interface{}) {
+	raw, err := decodeNumbers(payload)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	enc, err := decodeNumbers(b)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	compareValues(t, name, raw, enc)
+}
+
+// compareValues compares the value raw of a payload with the re-encoded value enc
+// at path.  The values decoded leniently, e.g. numbers and booleans in strings, are
+// compared with their decoded values; null and empty strings, decoded as no value,
+// are not compared.
+func compareValues(t *testing.T, path string, raw, enc interface{}) {
+	switch r := raw.(type) {
+	case nil:
+		return
+
+	case map[string]interface{}:
+		e, ok := enc.(map[string]interface{})
+		if !ok {
+			t.Errorf("%s: expected an object, got %v", path, enc)
+			return
+		}
+		for key, rv := range r {
+			ev, found := e[key]
+			for k, v := range e {
+				if !found && strings.EqualFold(k, key) {
+					ev, found = v, true
+				}
+			}
+			if !found && rv != nil {
+				t.Errorf("%s.%s: field lost", path, key)
+				continue
+			}
+			compareValues(t, path+"."+key, rv, ev)
+		}
+
+	case []interface{}:
+		e, ok := enc.([]interface{})
+		if !ok || len(e) != len(r) {
+			t.Errorf("%s: expected an array of %d, got %v", path, len(r), enc)
+			return
+		}
+		for i := range r {
+			compareValues(t, fmt.Sprintf("%s[%d]", path, i), r[i], e[i])
+		}
+
+	case string:
+		switch e := enc.(type) {
+		case string:
+			if e != r {
+				t.Errorf("%s: expected %q, got %q", path, r, e)
+			}
+		case json.Number:
+			if r != "" {
+				compareNumbers(t, path, json.Number(strings.TrimSpace(r)), e)
+			}
+		case bool:
+			if b, err := strconv.ParseBool(r); err != nil || b != e {
+				t.Errorf("%s: expected %q, got %v", path, r, e)
+			}
+		default:
+			t.Errorf("%s: expected %q, got %v", path, r, enc)
+		}
+
+	case json.Number:
+		switch e := enc.(type) {
+		case json.Number:
+			compareNumbers(t, path, r, e)
+		case bool:
+			if b, err := strconv.ParseBool(r.String()); err != nil || b != e {
+				t.Errorf("%s: expected %s, got %v", path, r, e)
+			}
+		default:
+			t.Errorf("%s: expected %s, got %v", path, r, enc)
+		}
+
+	default:
+		if !reflect.DeepEqual(raw, enc) {
+			t.Errorf("%s: expected %v, got %v", path, raw, enc)
+		}
+	}
+}
+
+// compareNumbers compares the numbers of a payload and the re-encoded value.
+func compareNumbers(t *testing.T, path string, raw, enc json.Number) {
+	r, rerr := strconv.ParseFloat(raw.String(), 64)
+	e, eerr := strconv.ParseFloat(enc.String(), 64)
+	if rerr != nil || eerr != nil || r != e {
+		t.Errorf("%s: expected %s, got %s", path, raw, enc)
+	}
+}
+
+func TestPayloadValues(t *testing.T) {
+	for pattern, typ := range map[string]reflect.Type{
+		"status*.json":     reflect.TypeOf(Status{}),
+		"flow*.json":       reflect.TypeOf(FlowData{}),
+		"agreements*.json": reflect.TypeOf([]Agreement{}),
+	} {
+		files, _ := filepath.Glob(filepath.Join("testdata", pattern))
+		for _, file := range files {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := reflect.New(typ).Interface()
+			if err := json.Unmarshal(b, v); err != nil {
+				t.Fatalf("%s: %v", file, err)
+			}
+			checkValues(t, file, b, v)
+		}
+	}
+}
+
+func TestTimeProperties(t *testing.T) {
+	for _, ms := range []int64{0, 1, -1, 999, 1553783612583, -62135596800000, math.MaxInt64, math.MinInt64} {
+		for _, in := range []string{strconv.FormatInt(ms, 10), strconv.Quote(strconv.FormatInt(ms, 10))} {
+			var tm Time
+			if err := json.Unmarshal([]byte(in), &tm); err != nil {
+				t.Errorf("%s: %v", in, err)
+				continue
+			}
+			if tm.UnixMilli() != ms {
+				t.Errorf("%s: decoded into %d", in, tm.UnixMilli())
+			}
+			if b, _ := json.Marshal(tm); string(b) != strconv.FormatInt(ms, 10) {
+				t.Errorf("%s: encoded into %s", in, b)
+			}
+		}
+	}
+
+	for _, in := range []string{`true`, `"abc"`, `{}`, `[]`, `"1e400"`, `1e400`} {
+		var tm Time
+		if err := json.Unmarshal([]byte(in), &tm); err == nil {
+			t.Errorf("%s: expect error, got %v", in, tm)
+		}
+	}
+}
+
+func TestJSONBoolProperties(t *testing.T) {
+	for in, expect := range map[string]bool{
+		`1`: true, `"1"`: true, `true`: true, `"true"`: true,
+		`0`: false, `"0"`: false, `false`: false, `"false"`: false,
+	} {
+		b := jsonBool(!expect)
+		if err := json.Unmarshal([]byte(in), &b); err != nil || bool(b) != expect {
+			t.Errorf("%s: decoded into %v, %v", in, b, err)
+		}
+	}
+
+	// null leaves the value unchanged
+	b := jsonBool(true)
+	if err := json.Unmarshal([]byte(`null`), &b); err != nil || !bool(b) {
+		t.Errorf("null: decoded into %v, %v", b, err)
+	}
+
+	for _, in := range []string{`2`, `"yes"`, `""`, `"\"1\""`, `{}`, `[1]`, `1.0`} {
+		var b jsonBool
+		if err := json.Unmarshal([]byte(in), &b); err == nil {
+			t.Errorf("%s: expect error, got %v", in, b)
+		}
+	}
+
+	// malformed JSON given directly
+	for _, in := range []string{`"1`, `1"`, `""1""`, `"`} {
+		var b jsonBool
+		if err := b.UnmarshalJSON([]byte(in)); err == nil {
+			t.Errorf("%s: expect error, got %v", in, b)
+		}
+	}
+}
+
+func TestPayloads(t *testing.T) {
+	for pattern, typ := range map[string]reflect.Type{
+		"status*.json":     reflect.TypeOf(Status{}),
+		"flow*.json":       reflect.TypeOf(FlowData{}),
+		"agreements*.json": reflect.TypeOf([]Agreement{}),
+	} {
+		files, _ := filepath.Glob(filepath.Join("testdata", pattern))
+		for _, file := range files {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(b, reflect.New(typ).Interface()); err != nil {
+				t.Errorf("%s: %v", file, err)
+			}
+			if _, err := checkSchema(b, typ); err != nil {
+				t.Errorf("%s: %v", file, err)
+			}
+		}
+	}
+
+	var s Status
+	b, _ := ioutil.ReadFile(filepath.Join("testdata", "status_strings.json"))
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ThermostatInfo.CurrentDisplayTemp != 1712 || s.ThermostatInfo.ActiveState != StateManual ||
+		!bool(s.GasUsage.IsSmart) || s.GasUsage.DayCost != 2.13 || s.ThermostatStates.LastUpdatedFromDisplay.UnixMilli() != 1553783612583 {
+		t.Errorf("unexpected status decoded from strings: %+v", s)
+	}
+}
+
+func FuzzTime(f *testing.F) {
+	for _, s := range []string{`1553783612583`, `"1553783612583"`, `null`, `-1`, `1.5e3`, `""`, `"x"`} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var tm Time
+		if err := json.Unmarshal(b, &tm); err != nil {
+			return
+		}
+		checkRoundTrip(t, &tm)
+	})
+}
+
+func FuzzJSONBool(f *testing.F) {
+	for _, s := range []string{`1`, `0`, `"1"`, `true`, `"false"`, `null`, `2`, `"\"1"`} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var v jsonBool
+		if err := json.Unmarshal(b, &v); err != nil {
+			return
+		}
+		checkRoundTrip(t, &v)
+	})
+}
+
+func FuzzInt(f *testing.F) {
+	for _, s := range []string{`34`, `"34"`, `34.5`, `"-1"`, `null`, `""`, `9223372036854775807`, `9223372036854775807.5`, `"1e3"`} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var i Int
+		if err := json.Unmarshal(b, &i); err != nil {
+			return
+		}
+		checkRoundTrip(t, &i)
+
+		// the Int is the rounded Float
+		var fl Float
+		if err := json.Unmarshal(b, &fl); err != nil {
+			t.Errorf("%s: decoded into Int %d but not into Float: %v", b, i, err)
+		}
+		if d := math.Abs(float64(i) - float64(fl)); d > 0.5 && d > 1e-9*math.Abs(float64(fl)) {
+			t.Errorf("%s: decoded into Int %d and Float %v", b, i, fl)
+		}
+	})
+}
+
+func FuzzStatus(f *testing.F) {
+	addPayloads(f, "status*.json")
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var s Status
+		if err := json.Unmarshal(b, &s); err != nil {
+			return
+		}
+		checkRoundTrip(t, &s)
+		checkSchema(b, reflect.TypeOf(s))
+	})
+}
+
+func FuzzFlowData(f *testing.F) {
+	addPayloads(f, "flow*.json")
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var fd FlowData
+		if err := json.Unmarshal(b, &fd); err != nil {
+			return
+		}
+		checkRoundTrip(t, &fd)
+		checkSchema(b, reflect.TypeOf(fd))
+	})
+}
+
+func FuzzAgreements(f *testing.F) {
+	addPayloads(f, "agreements*.json")
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var as []Agreement
+		if err := json.Unmarshal(b, &as); err != nil {
+			return
+		}
+		checkRoundTrip(t, &as)
+	})
+}
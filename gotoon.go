@@ -1,14 +1,20 @@
 package gotoon
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,27 +24,58 @@ const (
 	apiBaseURL   = "https://api.toon.eu/toon/v3"
 )
 
-// jsonTime defines customized JSON marshal and unmarshal functions
-// for converting timestamp into Time struct.
-type jsonTime time.Time
+// defaultTimeout is the default time limit of a HTTP request to the Toon API.
+const defaultTimeout = 10 * time.Second
+
+// Endpoints holds the URLs of the Toon API.  Empty URLs default to the ones of
+// the public Toon API.
+type Endpoints struct {
+	// AuthorizeURL is the URL of the authorization endpoint, e.g. https://api.toon.eu/authorize
+	AuthorizeURL string `json:"authorizeURL"`
+	// TokenURL is the URL of the token endpoint, e.g. https://api.toon.eu/token
+	TokenURL string `json:"tokenURL"`
+	// APIBaseURL is the base URL of the data endpoints, e.g. https://api.toon.eu/toon/v3
+	APIBaseURL string `json:"apiBaseURL"`
+}
+
+// withDefaults returns a copy of the Endpoints with empty URLs replaced by the
+// URLs of the public Toon API.
+func (e Endpoints) withDefaults() Endpoints {
+	if e.AuthorizeURL == "" {
+		e.AuthorizeURL = authorizeURL
+	}
+	if e.TokenURL == "" {
+		e.TokenURL = tokenURL
+	}
+	if e.APIBaseURL == "" {
+		e.APIBaseURL = apiBaseURL
+	}
+	return e
+}
+
+// Time is a timestamp of the Toon API.  It is encoded in JSON as an integer of
+// milliseconds since the Unix epoch, and is decoded without loss of precision,
+// so that a decoded struct can be re-encoded into the same JSON.
+type Time struct {
+	time.Time
+}
 
 // MarshalJSON marshals Time struct into timestamp integer in milliseconds.
-func (t jsonTime) MarshalJSON() ([]byte, error) {
-	return []byte(strconv.FormatInt(time.Time(t).Unix(), 10)), nil
+func (t Time) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(t.UnixMilli(), 10)), nil
 }
 
-// UnmarshalJSON unmarshals timestamp integer in milliseconds into Time struct.
-func (t *jsonTime) UnmarshalJSON(s []byte) (err error) {
-	q, err := strconv.ParseInt(string(s), 10, 64)
-	if err != nil {
+// UnmarshalJSON unmarshals timestamp integer in milliseconds, or a string of it,
+// into Time struct.  The time is in the local time zone.
+func (t *Time) UnmarshalJSON(s []byte) (err error) {
+	q, ok, err := parseInt(s)
+	if err != nil || !ok {
 		return err
 	}
-	*(*time.Time)(t) = time.Unix(q/1000, 0)
+	t.Time = time.UnixMilli(q)
 	return
 }
 
-func (t jsonTime) String() string { return time.Time(t).String() }
-
 // jsonBool defines customized JSON unmarshal function for converting
 // integer into boolean.
 type jsonBool bool
@@ -46,6 +83,12 @@ type jsonBool bool
 // UnmarshalJSON converts input string or integer into a boolean value.
 func (b *jsonBool) UnmarshalJSON(s []byte) (err error) {
 	bs := string(s)
+	if len(bs) >= 2 && bs[0] == '"' && bs[len(bs)-1] == '"' {
+		bs = bs[1 : len(bs)-1]
+	}
+	if bs == "null" {
+		return
+	}
 	if bs == "0" || bs == "false" {
 		*b = false
 	} else if bs == "1" || bs == "true" {
@@ -59,100 +102,112 @@ func (b *jsonBool) UnmarshalJSON(s []byte) (err error) {
 // token holds the data structure of the Toon API access token.
 type token struct {
 	AccessToken           string `json:"access_token"`
-	ExpiresIn             int    `json:"expires_in,string"`
+	ExpiresIn             Int    `json:"expires_in"`
 	ExpiresAt             time.Time
 	RefreshToken          string `json:"refresh_token"`
-	RefreshTokenExpiresIn int    `json:"refresh_token_expires_in,string"`
+	RefreshTokenExpiresIn Int    `json:"refresh_token_expires_in"`
 	RefreshTokenExpiresAt time.Time
 }
 
 // Agreement holds the data structure of the Toon API agreement. See https://developer.toon.eu/api-intro.
 type Agreement struct {
-	AgreementID            string `json:"agreementId"`
-	AgreementIDChecksum    string `json:"agreementIdChecksum"`
-	HeatingType            string `json:"heatingType"`
-	DisplayCommonName      string `json:"displayCommonName"`
-	DisplayHardwareVersion string `json:"displayHardwareVersion"`
-	DisplaySoftwareVersion string `json:"displaySoftwareVersion"`
-	IsToonSolar            bool   `json:"isToonSolar"`
-	IsToonly               bool   `json:"isToonly"`
+	AgreementID            string      `json:"agreementId"`
+	AgreementIDChecksum    string      `json:"agreementIdChecksum"`
+	HeatingType            HeatingType `json:"heatingType"`
+	DisplayCommonName      string      `json:"displayCommonName"`
+	DisplayHardwareVersion string      `json:"displayHardwareVersion"`
+	DisplaySoftwareVersion string      `json:"displaySoftwareVersion"`
+	IsToonSolar            bool        `json:"isToonSolar"`
+	IsToonly               bool        `json:"isToonly"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // ThermostatStates holds the data structure of the last states retrieved from
 // the getStatus interface of the Toon API.
 type ThermostatStates struct {
 	State                  []ThermostatState `json:"state"`
-	LastUpdatedFromDisplay jsonTime          `json:"lastUpdatedFromDisplay,int"`
+	LastUpdatedFromDisplay Time              `json:"lastUpdatedFromDisplay,int"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // ThermostatState holds the data structure of a state retrieved from the getStatus
 // interface of the Toon API.
 type ThermostatState struct {
-	ID        int `json:"id"`
-	TempValue int `json:"tempValue"`
-	Dhw       int `json:"dhw"`
+	ID        ActiveState `json:"id"`
+	TempValue Temperature `json:"tempValue"`
+	Dhw       Int         `json:"dhw"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // ThermostatInfo holds the data structure of the thermostat information retrieved
 // from the getStatus interface of the Toon API.
 type ThermostatInfo struct {
-	CurrentSetPoint        int      `json:"currentSetpoint"`
-	CurrentDisplayTemp     int      `json:"currentDisplayTemp"`
-	ProgramState           int      `json:"programState"`
-	ActiveState            int      `json:"activeState"`
-	NextProgram            int      `json:"nextProgram"`
-	NextState              int      `json:"nextState"`
-	NextTime               int      `json:"nextTime"`
-	NextSetPoint           int      `json:"nextSetpoint"`
-	ErrorFound             int      `json:"errorFound"`
-	BoilerModuleConnected  int      `json:"boilerModuleConnected"`
-	RealSetPoint           int      `json:"realSetpoint"`
-	BurnerInfo             string   `json:"burnerInfo"`
-	OtCommError            string   `json:"otCommError"`
-	CurrentModulationLevel int      `json:"currentModulationLevel"`
-	HaveOTBoiler           int      `json:"haveOTBoiler"`
-	LastUpdatedFromDisplay jsonTime `json:"lastUpdatedFromDisplay,int"`
+	CurrentSetPoint        Temperature  `json:"currentSetpoint"`
+	CurrentDisplayTemp     Temperature  `json:"currentDisplayTemp"`
+	ProgramState           ProgramState `json:"programState"`
+	ActiveState            ActiveState  `json:"activeState"`
+	NextProgram            ProgramState `json:"nextProgram"`
+	NextState              ActiveState  `json:"nextState"`
+	NextTime               Int          `json:"nextTime"`
+	NextSetPoint           Temperature  `json:"nextSetpoint"`
+	ErrorFound             Int          `json:"errorFound"`
+	BoilerModuleConnected  Int          `json:"boilerModuleConnected"`
+	RealSetPoint           Temperature  `json:"realSetpoint"`
+	BurnerInfo             string       `json:"burnerInfo"`
+	OtCommError            string       `json:"otCommError"`
+	CurrentModulationLevel Int          `json:"currentModulationLevel"`
+	HaveOTBoiler           Int          `json:"haveOTBoiler"`
+	LastUpdatedFromDisplay Time         `json:"lastUpdatedFromDisplay,int"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // PowerUsage holds the data structure of the current power consumption retrieved from
 // the getStatus interface of the Toon API.
 type PowerUsage struct {
-	Value                  int      `json:"value"`
-	DayCost                float32  `json:"dayCost,int"`
-	ValueProduced          int      `json:"valueProduced"`
-	DayCostProduced        int      `json:"dayCostProduced"`
-	ValueSolar             int      `json:"valueSolar"`
-	MaxSolar               int      `json:"maxSolar"`
-	DayCostSolar           int      `json:"dayCostSolar"`
-	AvgSolarValue          int      `json:"avgSolarValue"`
-	AvgValue               float32  `json:"avgValue"`
-	AvgDayValue            float32  `json:"avgDayValue"`
-	AvgProduValue          int      `json:"avgProduValue"`
-	AvgDayProduValue       int      `json:"avgDayProduValue"`
-	MeterReading           int      `json:"meterReading"`
-	MeterReadingLow        int      `json:"meterReadingLow"`
-	MeterReadingProdu      int      `json:"meterReadingProdu"`
-	MeterReadingLowProdu   int      `json:"meterReadingLowProdu"`
-	DayUsage               int      `json:"dayUsage"`
-	DayLowUsage            int      `json:"dayLowUsage"`
-	TodayLowestUsage       int      `json:"todayLowestUsage"`
+	Value                  Power    `json:"value"`
+	DayCost                Money    `json:"dayCost"`
+	ValueProduced          Power    `json:"valueProduced"`
+	DayCostProduced        Money    `json:"dayCostProduced"`
+	ValueSolar             Power    `json:"valueSolar"`
+	MaxSolar               Power    `json:"maxSolar"`
+	DayCostSolar           Money    `json:"dayCostSolar"`
+	AvgSolarValue          Power    `json:"avgSolarValue"`
+	AvgValue               Power    `json:"avgValue"`
+	AvgDayValue            Energy   `json:"avgDayValue"`
+	AvgProduValue          Power    `json:"avgProduValue"`
+	AvgDayProduValue       Power    `json:"avgDayProduValue"`
+	MeterReading           Energy   `json:"meterReading"`
+	MeterReadingLow        Energy   `json:"meterReadingLow"`
+	MeterReadingProdu      Energy   `json:"meterReadingProdu"`
+	MeterReadingLowProdu   Energy   `json:"meterReadingLowProdu"`
+	DayUsage               Energy   `json:"dayUsage"`
+	DayLowUsage            Energy   `json:"dayLowUsage"`
+	TodayLowestUsage       Energy   `json:"todayLowestUsage"`
 	IsSmart                jsonBool `json:"isSmart,int"`
-	LowestDayValue         int      `json:"lowestDayValue"`
-	SolarProducedToday     int      `json:"solarProducedToday"`
-	LastUpdatedFromDisplay jsonTime `json:"lastUpdatedFromDisplay,int"`
+	LowestDayValue         Energy   `json:"lowestDayValue"`
+	SolarProducedToday     Energy   `json:"solarProducedToday"`
+	LastUpdatedFromDisplay Time     `json:"lastUpdatedFromDisplay,int"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // GasUsage holds the data structure of the current gas consumption retrieved from
 // the getStatus interface of the Toon API.
 type GasUsage struct {
-	Value                  int      `json:"value"`
-	DayCost                float32  `json:"dayCost"`
-	AvgValue               float32  `json:"avgValue"`
-	MeterReading           int      `json:"meterReading"`
-	AvgDayValue            float32  `json:"avgDayValue"`
-	DayUsage               int      `json:"dayUsage"`
+	Value                  GasFlow  `json:"value"`
+	DayCost                Money    `json:"dayCost"`
+	AvgValue               GasFlow  `json:"avgValue"`
+	MeterReading           Volume   `json:"meterReading"`
+	AvgDayValue            Volume   `json:"avgDayValue"`
+	DayUsage               Volume   `json:"dayUsage"`
 	IsSmart                jsonBool `json:"isSmart,int"`
-	LastUpdatedFromDisplay jsonTime `json:"lastUpdatedFromDisplay,int"`
+	LastUpdatedFromDisplay Time     `json:"lastUpdatedFromDisplay,int"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // Status holds the main data structure of the current Toon device status retrieved
@@ -162,14 +217,19 @@ type Status struct {
 	ThermostatInfo        ThermostatInfo   `json:"thermostatInfo"`
 	PowerUsage            PowerUsage       `json:"powerUsage"`
 	GasUsage              GasUsage         `json:"gasUsage"`
-	LastUpdateFromDisplay jsonTime         `json:"lastUpdateFromDisplay,int"`
+	LastUpdateFromDisplay Time             `json:"lastUpdateFromDisplay,int"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // FlowDataPoint holds the data structure of the consumption data points.
 type FlowDataPoint struct {
-	Timestamp jsonTime `json:"timestamp,int"`
-	Unit      string   `json:"unit"`
-	Value     float32  `json:"value"`
+	Timestamp Time   `json:"timestamp,int"`
+	Unit      string `json:"unit"`
+	// Value is the consumption in the data point in the Unit, see Volume.
+	Value Float `json:"value"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // FlowData holds the data structure of the consumption data.
@@ -179,6 +239,8 @@ type FlowData struct {
 	Weeks  []FlowDataPoint `json:"weeks"`
 	Months []FlowDataPoint `json:"months"`
 	Years  []FlowDataPoint `json:"years"`
+	// Extra holds the fields unknown to the library.
+	Extra Extra `json:"-"`
 }
 
 // Toon provides interface to access and retrieve data from the Toon device,
@@ -188,21 +250,249 @@ type Toon struct {
 	Username string
 	// Password is the password for the Tenant account (e.g. the Mijn Eneco password)
 	Password string
+	// Credentials provides the Username, Password and ConsumerSecret when they are needed
+	// to login or refresh the access token, so that they need not to be set in the Toon.
+	// If nil, the Username, Password and ConsumerSecret fields are used.
+	Credentials CredentialProvider
 	// TenantID is the tenant ID (e.g. eneco, viesgo)
 	TenantID string
 	// ConsumerKey is the consumer key of the Toon API, see https://developer.toon.eu/authentication
 	ConsumerKey string
 	// ConsumerSecret is the consumer secret of the Toon API, see https://developer.toon.eu/authentication
 	ConsumerSecret string
+	// Endpoints are the URLs of the Toon API.  If empty, the public Toon API is used.
+	Endpoints Endpoints
+	// HTTPClient is the HTTP client used to make requests.  If nil, a new client with
+	// the Timeout is created for every call.
+	HTTPClient *http.Client
+	// Timeout is the time limit of a HTTP request made with the default HTTP client.
+	// If zero, the time limit is 10 seconds.  It is not used when HTTPClient is set.
+	Timeout time.Duration
+	// TokenStore persists the access token between sessions, so that a new session
+	// needs not to login again.  If nil, the access token is only kept in memory.
+	TokenStore TokenStore
+	// RetryPolicy defines how calls failed with transient errors are retried.
+	// If nil, failed calls are not retried.
+	RetryPolicy *RetryPolicy
+	// RateLimit throttles the calls made with the ConsumerKey of the Toon to stay within
+	// the request quota of the Toon API.  If nil, calls are not throttled.
+	RateLimit *RateLimit
+	// Cache keeps responses of the Toon API to avoid repeated calls.  If nil, responses
+	// are not cached.
+	Cache *Cache
+	// Logger receives the log of the requests and the authentication events.  Secrets,
+	// e.g. the password and the tokens, are never logged.  If nil, nothing is logged.
+	Logger *slog.Logger
+	// Interceptors are run around every HTTP request made to the Toon API, including
+	// the requests for authentication.  The first Interceptor is the outermost one.
+	Interceptors []Interceptor
+	// OnTokenEvent is called on the login, the refresh of the access token and their
+	// failures, see TokenEvent.  It is called synchronously and must not call the Toon.
+	OnTokenEvent func(e TokenEvent)
+	// RefreshExpiryWarning is the period before the expiry of the refresh token in which
+	// a TokenRefreshExpiring event is emitted.  If zero, the event is not emitted.
+	RefreshExpiryWarning time.Duration
+	// Location is the time zone in which the times of the Status and FlowData are
+	// presented, e.g. the one of DeviceLocation.  If nil, the local time zone is used.
+	Location *time.Location
+	// StrictDecoding makes the calls fail with a SchemaDriftError when a response of
+	// the Toon API doesn't match the models of the library, e.g. a field is renamed.
+	StrictDecoding bool
+	// OnSchemaDrift is called with the differences between a response of the Toon API
+	// and the models of the library, e.g. to report them as warnings.  If nil, and
+	// StrictDecoding is not set, the responses are not checked.
+	OnSchemaDrift func(e *SchemaDriftError)
+	// Concurrency is the maximum number of concurrent requests made by GetStatusAll.
+	// If zero, at most 4 requests are made concurrently.
+	Concurrency int
+	// Clock returns the current time used for the expiry of the tokens.  If nil,
+	// time.Now is used.  It allows testing the token lifecycle with a fake clock.
+	Clock func() time.Time
 	// accessToken is the current Toon API access token, see https://developer.toon.eu/authentication
 	accessToken token
+	// tokenMu guards the accessToken; it is a pointer so that the Toon can be
+	// formatted by value.
+	tokenMu *sync.Mutex
+	// expiryWarned is the expiry time of the refresh token for which the
+	// TokenRefreshExpiring event has been emitted.
+	expiryWarned time.Time
+}
+
+// APIError is returned when the Toon API responds with an unexpected HTTP status code.
+type APIError struct {
+	// Method is the HTTP method of the failed request.
+	Method string
+	// URL is the URL of the failed request, without query parameters.
+	URL string
+	// StatusCode is the HTTP status code returned by the Toon API.
+	StatusCode int
+	// Body is the response body returned by the Toon API.
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s error: %s", e.Method, string(e.Body))
+}
+
+// request describes a HTTP request to the Toon API.  A new http.Request is
+// constructed from it for every attempt, so that a retry never reuses a
+// consumed request body.
+type request struct {
+	// endpoint is the name of the API endpoint, e.g. EndpointStatus.
+	endpoint string
+	// agreementID is the agreement the request refers to, if any.
+	agreementID string
+	method      string
+	url         string
+	query       url.Values
+	form        url.Values
+	// auth indicates whether the request is authorised with the access token.
+	auth bool
+	// write indicates whether the request is not idempotent, e.g. it changes
+	// the state of the Toon device.
+	write bool
+	// login indicates a request of the login or the token refresh.  It is not
+	// idempotent, but changes no state of the Toon device; it is retried when it
+	// fails without a response, even if writes are not retried.
+	login bool
+	// accessToken is the access token authorising the request.
+	accessToken string
+}
+
+// newHTTPRequest constructs a http.Request out of the request r.
+func (r request) newHTTPRequest(ctx context.Context) (req *http.Request, err error) {
+
+	var body io.Reader
+	if r.form != nil {
+		body = strings.NewReader(r.form.Encode())
+	}
+
+	req, err = http.NewRequestWithContext(ctx, r.method, r.url, body)
+	if err != nil {
+		return
+	}
+	// add query parameter values to the request
+	req.URL.RawQuery = r.query.Encode()
+
+	// set request header
+	if r.auth {
+		req.Header.Set("authorization", "Bearer "+r.accessToken)
+		req.Header.Set("accept", "application/json")
+		req.Header.Set("cache-control", "no-cache")
+	}
+	if r.form != nil {
+		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	} else if r.auth {
+		req.Header.Set("content-type", "application/json")
+	}
+
+	return
+}
+
+// send makes the request r using the HTTP client c.  Requests are throttled
+// following the RateLimit of the Toon, and requests failed with a transient error,
+// including a response body cut short, are retried following the RetryPolicy of
+// the Toon.  The body of the returned response is read into memory.
+//
+// On success, the caller is responsible for closing the response body.
+func (t *Toon) send(ctx context.Context, c *http.Client, r request) (res *http.Response, err error) {
+
+	p := t.RetryPolicy
+	l := t.limiter()
+	limited := 0
+
+	for attempt := 1; ; attempt++ {
+		if l != nil {
+			if err = l.take(ctx, t.RateLimit.FailFast); err != nil {
+				return
+			}
+		}
+
+		var req *http.Request
+		req, err = r.newHTTPRequest(ctx)
+		if err != nil {
+			return
+		}
+
+		start := time.Now()
+		res, err = t.intercept(c, r, req)
+
+		// read the body, so that a response cut short is a failed attempt
+		if err == nil {
+			var body []byte
+			body, err = ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				res = nil
+			} else {
+				res.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+		}
+
+		attrs := append(requestAttrs(r),
+			slog.Int("attempt", attempt),
+			slog.Duration("latency", time.Since(start)),
+		)
+		if err != nil {
+			t.log(ctx, slog.LevelWarn, "request failed", append(attrs, slog.Any("error", err))...)
+		} else {
+			t.log(ctx, slog.LevelDebug, "request done", append(attrs, slog.Int("status", res.StatusCode))...)
+		}
+
+		// 429 TOO MANY REQUESTS: wait for the quota to be renewed, if the rate
+		// limit is blocking.
+		if err == nil && res.StatusCode == http.StatusTooManyRequests {
+			wait := retryAfter(res)
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+			res = nil
+
+			if l == nil || t.RateLimit.FailFast || limited >= maxRateLimitedRetries {
+				err = &RateLimitError{RetryAfter: wait}
+				return
+			}
+			t.log(ctx, slog.LevelWarn, "rate limited by the Toon API", append(requestAttrs(r), slog.Duration("retryAfter", wait))...)
+			l.pause(time.Now().Add(wait))
+			limited++
+			attempt--
+			continue
+		}
+
+		// a request still being processed is repeated even without a RetryPolicy
+		q := p
+		if q == nil && res != nil && res.StatusCode == http.StatusAccepted {
+			q = acceptedPolicy
+		}
+		if !q.shouldRetry(r, attempt, res, err) {
+			return
+		}
+
+		// discard the response of the failed attempt
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		d := q.backoff(attempt)
+		t.log(ctx, slog.LevelInfo, "retrying request", append(requestAttrs(r), slog.Int("attempt", attempt), slog.Duration("backoff", d))...)
+		if err = sleep(ctx, d); err != nil {
+			return
+		}
+	}
 }
 
 // getAccessToken authorise the user to get the access token for retriving data
 // from the Toon device.
-func (t *Toon) getAccessToken() (err error) {
+func (t *Toon) getAccessToken(ctx context.Context) (err error) {
 
-	c := newHTTPSClient()
+	c := t.httpClient()
+	ep := t.Endpoints.withDefaults()
+
+	// the credentials are only kept during the login
+	cred, err := t.credentials(ctx)
+	if err != nil {
+		return
+	}
 
 	// step 1: call https://api.toon.eu/authorize (optionally?)
 	//         with input: client_id, response_type=code, redirect_url=http://127.0.0.1, tenant_id
@@ -224,22 +514,26 @@ func (t *Toon) getAccessToken() (err error) {
 	v := url.Values{}
 	v.Set("client_id", t.ConsumerKey)
 	v.Set("tenant_id", t.TenantID)
-	v.Set("username", t.Username)
-	v.Set("password", t.Password)
+	v.Set("username", cred.Username)
+	v.Set("password", cred.Password)
 	v.Set("response_type", "code")
 	v.Set("state", "")
 	v.Set("scope", "")
 
-	// disable http redirect
+	// disable http redirect on a copy of the client
+	cc := *c
+	c = &cc
 	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		return http.ErrUseLastResponse
 	}
-	r, err := c.PostForm(authorizeURL+"/legacy", v)
+	r, err := t.send(ctx, c, request{endpoint: EndpointAuthorize, method: "POST", url: ep.AuthorizeURL + "/legacy", form: v, write: true, login: true})
 	if err != nil {
 		return
 	}
+	r.Body.Close()
 	if r.StatusCode != 302 {
 		err = errors.New("invalid consumer key")
+		t.log(ctx, slog.LevelWarn, "login failed", slog.String("tenantID", t.TenantID), slog.Int("status", r.StatusCode))
 		return
 	}
 
@@ -251,36 +545,53 @@ func (t *Toon) getAccessToken() (err error) {
 	code := u.Query().Get("code")
 	if code == "" {
 		err = fmt.Errorf("fail extracting code, header: +%v", r.Header)
+		return
 	}
 
 	// step 3: call https://api.toon.eu/token to get the access token
 	v = url.Values{}
 	v.Set("client_id", t.ConsumerKey)
-	v.Set("client_secret", t.ConsumerSecret)
+	v.Set("client_secret", cred.ConsumerSecret)
 	v.Set("grant_type", "authorization_code")
 	v.Set("code", code)
 
 	// current time
-	tnow := time.Now()
-	r, err = c.PostForm(tokenURL, v)
+	tnow := t.now()
+	r, err = t.send(ctx, c, request{endpoint: EndpointToken, method: "POST", url: ep.TokenURL, form: v, write: true, login: true})
 	if err != nil {
 		return
 	}
+	defer r.Body.Close()
 
 	bodyBytes, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		return
 	}
+	if r.StatusCode != 200 {
+		err = &APIError{Method: "POST", URL: ep.TokenURL, StatusCode: r.StatusCode, Body: bodyBytes}
+		return
+	}
 
 	// unmarshal response body to Token struct
-	if err = json.Unmarshal(bodyBytes, &(t.accessToken)); err != nil {
+	var tk token
+	if err = json.Unmarshal(bodyBytes, &tk); err != nil {
 		return
 	}
+	t.accessToken = tk
 
 	// derive ExpiresAt = tnow + (ExpiresIn - 180)s
 	t.accessToken.ExpiresAt = tnow.Add(time.Second * time.Duration(t.accessToken.ExpiresIn-180))
 	t.accessToken.RefreshTokenExpiresAt = tnow.Add(time.Second * time.Duration(t.accessToken.RefreshTokenExpiresIn-180))
 
+	t.saveToken(ctx)
+	t.emit(TokenLogin, nil)
+
+	t.log(ctx, slog.LevelInfo, "logged in",
+		slog.String("tenantID", t.TenantID),
+		slog.Time("expiresAt", t.accessToken.ExpiresAt),
+		slog.Time("refreshTokenExpiresAt", t.accessToken.RefreshTokenExpiresAt),
+	)
+
 	return
 }
 
@@ -288,74 +599,157 @@ func (t *Toon) getAccessToken() (err error) {
 // The function doesn't check the validity of the refresh_token; thus the caller must ensure it.
 //
 // Once the token is successfully refreshed, the accessToken is updated.
-func (t *Toon) refreshAccessToken() (err error) {
+func (t *Toon) refreshAccessToken(ctx context.Context) (err error) {
+	cred, err := t.credentials(ctx)
+	if err != nil {
+		return
+	}
+
 	v := url.Values{}
 	v.Set("client_id", t.ConsumerKey)
-	v.Set("client_secret", t.ConsumerSecret)
+	v.Set("client_secret", cred.ConsumerSecret)
 	v.Set("grant_type", "refresh_token")
 	v.Set("refresh_token", t.accessToken.RefreshToken)
 
-	c := newHTTPSClient()
-	r, err := c.PostForm(tokenURL, v)
+	tokenURL := t.Endpoints.withDefaults().TokenURL
+
+	c := t.httpClient()
+	r, err := t.send(ctx, c, request{endpoint: EndpointToken, method: "POST", url: tokenURL, form: v, write: true, login: true})
 	if err != nil {
 		return
 	}
+	defer r.Body.Close()
+
 	bodyBytes, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		return
 	}
+	if r.StatusCode != 200 {
+		err = &APIError{Method: "POST", URL: tokenURL, StatusCode: r.StatusCode, Body: bodyBytes}
+		return
+	}
 
 	// unmarshal response body to Token struct
-	if err = json.Unmarshal(bodyBytes, &(t.accessToken)); err != nil {
+	var tk token
+	if err = json.Unmarshal(bodyBytes, &tk); err != nil {
 		return
 	}
+	t.accessToken = tk
 
 	// derive ExpiresAt = tnow + (ExpiresIn - 180)s
-	tnow := time.Now()
+	tnow := t.now()
 	t.accessToken.ExpiresAt = tnow.Add(time.Second * time.Duration(t.accessToken.ExpiresIn-180))
 	t.accessToken.RefreshTokenExpiresAt = tnow.Add(time.Second * time.Duration(t.accessToken.RefreshTokenExpiresIn-180))
 
+	t.saveToken(ctx)
+	t.emit(TokenRefresh, nil)
+
+	t.log(ctx, slog.LevelInfo, "refreshed access token",
+		slog.Time("expiresAt", t.accessToken.ExpiresAt),
+		slog.Time("refreshTokenExpiresAt", t.accessToken.RefreshTokenExpiresAt),
+	)
+
 	return
 }
 
-func (t *Toon) hasValidToken() (isValid bool) {
+// authorize returns a valid access token, refreshing the token or logging in if
+// necessary.  Concurrent calls are serialised, so that only one of them logs in.
+func (t *Toon) authorize(ctx context.Context) (accessToken string, err error) {
+	mu := t.tokenLock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !t.hasValidToken(ctx) {
+		if err = t.getAccessToken(ctx); err != nil {
+			return
+		}
+	}
+	accessToken = t.accessToken.AccessToken
+	return
+}
+
+// tokenLock returns the mutex guarding the accessToken, creating it if needed.
+func (t *Toon) tokenLock() *sync.Mutex {
+	tokenLockInit.Lock()
+	defer tokenLockInit.Unlock()
+
+	if t.tokenMu == nil {
+		t.tokenMu = &sync.Mutex{}
+	}
+	return t.tokenMu
+}
+
+// now returns the current time following the Clock of the Toon.
+func (t *Toon) now() time.Time {
+	if t.Clock != nil {
+		return t.Clock()
+	}
+	return time.Now()
+}
 
-	// the accessToken is not set
-	if &(t.accessToken) == nil {
+// expireToken marks the access token as expired, so that it is renewed by the
+// next authorize.  It is a no-op if the access token has already been renewed.
+func (t *Toon) expireToken(accessToken string) {
+	mu := t.tokenLock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if t.accessToken.AccessToken == accessToken {
+		t.accessToken.ExpiresAt = time.Time{}
+	}
+}
+
+// tokenLockInit guards the creation of the token mutex of a Toon.
+var tokenLockInit sync.Mutex
+
+func (t *Toon) hasValidToken(ctx context.Context) (isValid bool) {
+
+	// the accessToken is not set; try loading it from the TokenStore
+	if t.accessToken.AccessToken == "" && !t.loadToken(ctx) {
 		isValid = false
 		return
 	}
 
 	// the refresh token has been expired
-	if t.accessToken.RefreshTokenExpiresAt.Before(time.Now()) {
+	if t.accessToken.RefreshTokenExpiresAt.Before(t.now()) {
+		t.emit(TokenRefreshExpired, nil)
 		isValid = false
 		return
 	}
 
+	// the refresh token is about to expire
+	t.checkRefreshExpiry()
+
 	// the token has expired; but we can try to renew the token
-	if t.accessToken.ExpiresAt.Before(time.Now()) {
+	if t.accessToken.ExpiresAt.Before(t.now()) {
 		// given the refresh token is still valid, try refreshing the access token.
-		if err := t.refreshAccessToken(); err != nil {
+		if err := t.refreshAccessToken(ctx); err != nil {
+			t.log(ctx, slog.LevelWarn, "fail refreshing access token", slog.Any("error", err))
+			t.emit(TokenRefreshFailed, err)
 			isValid = false
 			return
 		}
 	}
 
 	// finally check whether the current/refreshed access token is valid.
-	isValid = t.accessToken.ExpiresAt.Before(time.Now())
+	isValid = t.accessToken.ExpiresAt.After(t.now())
 	return
 }
 
 // GetAgreements gets identifier information of accessible Toon devices.
 func (t *Toon) GetAgreements() (agreements []Agreement, err error) {
+	return t.getAgreements(context.Background())
+}
+
+func (t *Toon) getAgreements(ctx context.Context) (agreements []Agreement, err error) {
 
 	var bodyBytes []byte
-	bodyBytes, err = t.apiGet(apiBaseURL+"/agreements", url.Values{})
+	bodyBytes, err = t.apiGet(ctx, "", EndpointAgreements, url.Values{})
 	if err != nil {
 		return
 	}
 
-	err = json.Unmarshal(bodyBytes, &agreements)
+	err = t.decode(ctx, EndpointAgreements, bodyBytes, &agreements)
 	return
 }
 
@@ -366,6 +760,10 @@ func (t *Toon) GetAgreements() (agreements []Agreement, err error) {
 // The information is retrieved via the Toon API endpoint:
 // https://api.toon.eu/toon/v3/{agreement.AgreementID}/status
 func (t *Toon) GetStatus(agreement Agreement) (status Status, err error) {
+	return t.getStatus(context.Background(), agreement)
+}
+
+func (t *Toon) getStatus(ctx context.Context, agreement Agreement) (status Status, err error) {
 
 	if &(agreement.AgreementID) == nil {
 		err = fmt.Errorf("Invalid agreement: %+v", agreement)
@@ -373,12 +771,15 @@ func (t *Toon) GetStatus(agreement Agreement) (status Status, err error) {
 	}
 
 	var bodyBytes []byte
-	bodyBytes, err = t.apiGet(apiBaseURL+"/"+agreement.AgreementID+"/status", url.Values{})
+	bodyBytes, err = t.apiGet(ctx, agreement.AgreementID, EndpointStatus, url.Values{})
 	if err != nil {
 		return
 	}
 
-	err = json.Unmarshal(bodyBytes, &status)
+	if err = t.decode(ctx, EndpointStatus, bodyBytes, &status); err != nil {
+		return
+	}
+	status.setLocation(t.Location)
 
 	return
 }
@@ -398,112 +799,148 @@ func (t *Toon) GetGasFlow(agreement Agreement, fromTime, toTime time.Time) (flow
 
 	v := url.Values{}
 	if (time.Time{}) != fromTime {
-		v.Add("fromTime", fmt.Sprintf("%d", 1000*fromTime.Unix()))
+		v.Add("fromTime", fmt.Sprintf("%d", fromTime.UnixMilli()))
 	}
 	if (time.Time{}) != toTime {
-		v.Add("toTime", fmt.Sprintf("%d", 1000*toTime.Unix()))
+		v.Add("toTime", fmt.Sprintf("%d", toTime.UnixMilli()))
 	}
 
 	var bodyBytes []byte
-	bodyBytes, err = t.apiGet(apiBaseURL+"/"+agreement.AgreementID+"/consumption/gas/flows", v)
+	bodyBytes, err = t.apiGet(context.Background(), agreement.AgreementID, EndpointGasFlows, v)
 	if err != nil {
 		return
 	}
 
-	err = json.Unmarshal(bodyBytes, &flow)
+	if err = t.decode(context.Background(), EndpointGasFlows, bodyBytes, &flow); err != nil {
+		return
+	}
+	flow.setLocation(t.Location)
 
 	return
 }
 
-// apiGet is a generic method for making GET request to the given API URL with optional
-// query parameters.
+// apiGet is a generic method for making GET request to the given API endpoint of an
+// agreement with optional query parameters.  Requests failed with a transient error are
+// retried following the RetryPolicy of the Toon, and responses are cached following the
+// Cache of the Toon.  A request rejected with an invalid access token is repeated once
+// with a renewed token.
 // On success (http status code 200), it returns the response body in byte slice; othewise
 // the error.
-func (t *Toon) apiGet(apiURL string, query url.Values) (httpBodyBytes []byte, err error) {
+func (t *Toon) apiGet(ctx context.Context, agreementID, endpoint string, query url.Values) (httpBodyBytes []byte, err error) {
 
-	if !t.hasValidToken() {
-		if err = t.getAccessToken(); err != nil {
-			return
-		}
+	k := cacheKey(agreementID, endpoint, query.Encode())
+	if b, ok := t.Cache.get(k); ok {
+		t.log(ctx, slog.LevelDebug, "cache hit", slog.String("endpoint", endpoint), slog.String("agreementID", agreementID))
+		return b, nil
 	}
 
-	c := newHTTPSClient()
+	apiURL := t.endpointURL(agreementID, endpoint)
 
-	for {
-		var req *http.Request
+	accessToken, err := t.authorize(ctx)
+	if err != nil {
+		return
+	}
+
+	c := t.httpClient()
+
+	for reauthorized := false; ; reauthorized = true {
 		var res *http.Response
 
-		req, err = http.NewRequest("GET", apiURL, nil)
+		// make request; requests still being processed are repeated by send
+		res, err = t.send(ctx, c, request{endpoint: endpoint, agreementID: agreementID, method: "GET", url: apiURL, query: query, auth: true, accessToken: accessToken})
 		if err != nil {
 			return
 		}
-		// add query parameter values to the request
-		req.URL.RawQuery = query.Encode()
 
-		// set request header
-		req.Header.Set("authorization", "Bearer "+t.accessToken.AccessToken)
-		req.Header.Set("accept", "application/json")
-		req.Header.Set("cache-control", "no-cache")
-		req.Header.Set("content-type", "application/json")
-
-		// make request
-		res, err = c.Do(req)
+		httpBodyBytes, err = ioutil.ReadAll(res.Body)
+		res.Body.Close()
 		if err != nil {
 			return
 		}
 
-		// 202 ACCEPTED
-		if res.StatusCode == 202 {
-			// request accepted but server is still processing the request.
+		if res.StatusCode == 401 && !reauthorized {
+			// the access token is rejected, e.g. revoked by the server; renew it once.
+			t.log(ctx, slog.LevelWarn, "access token rejected, renewing it", slog.String("url", apiURL))
+			t.expireToken(accessToken)
+			if accessToken, err = t.authorize(ctx); err != nil {
+				return
+			}
 			continue
 		}
 
-		httpBodyBytes, err = ioutil.ReadAll(res.Body)
-		if err != nil {
+		if res.StatusCode != 200 {
+			// other code: 202 still processing, 4xx, 5xx, etc.
+			err = &APIError{Method: "GET", URL: apiURL, StatusCode: res.StatusCode, Body: httpBodyBytes}
 			return
 		}
 
-		// 200 OK
-		if res.StatusCode == 200 {
-			// the HTTP call is successful
-			break
-		}
-
-		// other code: 4xx, 5xx, etc.
-		err = fmt.Errorf("GET error: %s", string(httpBodyBytes))
+		// the HTTP call is successful
+		t.Cache.put(k, agreementID, endpoint, httpBodyBytes)
+		return
 	}
-
-	return
 }
 
-// apiPostForm is a generic method for making Form POST request to the given API URL with provided
-// formData.
+// apiPostForm is a generic method for making Form POST request to the given API endpoint of an
+// agreement with provided formData.  The request is only retried if the RetryPolicy of the Toon
+// allows retrying writes.  Responses of the agreement in the Cache of the Toon are invalidated.
 // On success (http status code 200), it returns the response body in byte slice; othewise
 // the error.
-func (t *Toon) apiPostForm(apiURL string, formData url.Values) (httpBodyBytes []byte, err error) {
+func (t *Toon) apiPostForm(ctx context.Context, agreementID, endpoint string, formData url.Values) (httpBodyBytes []byte, err error) {
 
-	if !t.hasValidToken() {
-		if err = t.getAccessToken(); err != nil {
-			return
-		}
+	apiURL := t.endpointURL(agreementID, endpoint)
+
+	// the write may change any state of the agreement
+	defer t.Cache.invalidate(agreementID)
+
+	accessToken, err := t.authorize(ctx)
+	if err != nil {
+		return
 	}
 
-	c := newHTTPSClient()
-	res, err := c.PostForm(apiURL, formData)
+	c := t.httpClient()
+	res, err := t.send(ctx, c, request{endpoint: endpoint, agreementID: agreementID, method: "POST", url: apiURL, form: formData, auth: true, write: true, accessToken: accessToken})
 	if err != nil {
 		return
 	}
+	defer res.Body.Close()
 
 	httpBodyBytes, err = ioutil.ReadAll(res.Body)
 	if err != nil {
 		return
 	}
 
+	if res.StatusCode != 200 {
+		err = &APIError{Method: "POST", URL: apiURL, StatusCode: res.StatusCode, Body: httpBodyBytes}
+	}
+
 	return
 }
 
 // internal utility functions
-func newHTTPSClient() (client *http.Client) {
+
+// endpointURL returns the URL of the API endpoint of the given agreement.  The endpoint
+// is not specific to an agreement if agreementID is empty.
+func (t *Toon) endpointURL(agreementID, endpoint string) string {
+	base := t.Endpoints.withDefaults().APIBaseURL
+	if agreementID == "" {
+		return base + "/" + endpoint
+	}
+	return base + "/" + agreementID + "/" + endpoint
+}
+
+// httpClient returns the HTTPClient of the Toon, or a new client if it is not set.
+func (t *Toon) httpClient() *http.Client {
+	if t.HTTPClient != nil {
+		return t.HTTPClient
+	}
+	return newHTTPSClient(t.Timeout)
+}
+
+func newHTTPSClient(timeout time.Duration) (client *http.Client) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
 	transport := &http.Transport{
 		DialContext: (&net.Dialer{
 			Timeout: 5 * time.Second,
@@ -512,9 +949,21 @@ func newHTTPSClient() (client *http.Client) {
 	}
 
 	client = &http.Client{
-		Timeout:   10 * time.Second,
+		Timeout:   timeout,
 		Transport: transport,
 	}
 
 	return
 }
+
+// sleep pauses the current goroutine for the duration d, or until the context ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
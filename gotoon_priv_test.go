@@ -1,12 +1,21 @@
 package gotoon
 
 import (
+	"context"
 	"os"
 	"testing"
 )
 
 var toon Toon
 
+// skipOffline skips the test if the TOONAPI_TEST_* credentials of a real account
+// are not set.  The login and refresh are then covered by the tests of gotoontest.
+func skipOffline(t *testing.T) {
+	if toon.Username == "" {
+		t.Skip("TOONAPI_TEST_USERNAME not set")
+	}
+}
+
 func init() {
 	toon = Toon{
 		Username:       os.Getenv("TOONAPI_TEST_USERNAME"),
@@ -18,7 +27,9 @@ func init() {
 }
 
 func TestGetAccessToken(t *testing.T) {
-	err := toon.getAccessToken()
+	skipOffline(t)
+
+	err := toon.getAccessToken(context.Background())
 	if err != nil {
 		t.Errorf("Fail getting access token: %+v\n", err)
 	}
@@ -26,10 +37,11 @@ func TestGetAccessToken(t *testing.T) {
 }
 
 func TestRefreshAccessToken(t *testing.T) {
+	skipOffline(t)
 
 	oldToken := toon.accessToken
 
-	err := toon.refreshAccessToken()
+	err := toon.refreshAccessToken(context.Background())
 	if err != nil {
 		t.Errorf("Fail getting agreements: %+v\n", err)
 	}
@@ -39,3 +51,20 @@ func TestRefreshAccessToken(t *testing.T) {
 	}
 	t.Logf("%+v\n", toon.accessToken)
 }
+
+func TestJSONBool(t *testing.T) {
+	for in, expect := range map[string]bool{`1`: true, `"true"`: true, `0`: false, `"false"`: false} {
+		b := jsonBool(!expect)
+		if err := b.UnmarshalJSON([]byte(in)); err != nil || bool(b) != expect {
+			t.Errorf("%s: decoded into %v, %v", in, b, err)
+		}
+	}
+
+	// only a single pair of quotes is stripped
+	for _, in := range []string{`"1`, `1"`, `""1""`, `"`, `"yes"`} {
+		var b jsonBool
+		if err := b.UnmarshalJSON([]byte(in)); err == nil {
+			t.Errorf("%s: malformed value decoded into %v", in, b)
+		}
+	}
+}
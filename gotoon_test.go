@@ -1,12 +1,14 @@
 package gotoon_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/hurngchunlee/gotoon"
+	"github.com/hurngchunlee/gotoon/gotoontest"
 )
 
 var toon gotoon.Toon
@@ -21,6 +23,20 @@ func init() {
 	}
 }
 
+// TestMain runs the tests against the fake Toon API of gotoontest, unless the
+// TOONAPI_TEST_* credentials of a real account are set.
+func TestMain(m *testing.M) {
+	if toon.Username != "" {
+		os.Exit(m.Run())
+	}
+
+	s := gotoontest.NewServer()
+	toon = *s.Toon()
+	code := m.Run()
+	s.Close()
+	os.Exit(code)
+}
+
 func TestGetAgreements(t *testing.T) {
 
 	agreements, err := toon.GetAgreements()
@@ -139,3 +155,49 @@ func ExampleToon_GetGasFlow() {
 		fmt.Printf("Gas flow %s: %+v\n", agreement.AgreementID, flow)
 	}
 }
+
+// The code below shows how to create a validated Toon with options, keeping the
+// access token in a file between sessions.
+func ExampleNewToon() {
+	toon, err := gotoon.NewToon(
+		gotoon.WithTenant("eneco"),
+		gotoon.WithCredentials("myEnecoUsername", "myEnecoPassword"),
+		gotoon.WithConsumer("ToonAPIConsumerKey", "ToonAPIConsumerSecret"),
+		gotoon.WithTokenStore(gotoon.FileTokenStore("/tmp/toon-token.json")),
+		gotoon.WithTimeout(30*time.Second),
+	)
+	if err != nil {
+		fmt.Printf("Invalid configuration: %+v\n", err)
+		return
+	}
+
+	agreements, _ := toon.GetAgreements()
+	for _, agreement := range agreements {
+		fmt.Printf("%+v", agreement)
+	}
+}
+
+// The code below shows how to get current status of all Toon devices concurrently.
+func ExampleToon_GetStatusAll() {
+	toon := gotoon.Toon{
+		Username:       "myEnecoUsername",
+		Password:       "myEnecoPassword",
+		TenantID:       "eneco",
+		ConsumerKey:    "ToonAPIConsumerKey",
+		ConsumerSecret: "ToonAPIConsumerSecret",
+		Concurrency:    8,
+	}
+
+	results, err := toon.GetStatusAll(context.Background())
+	if err != nil {
+		fmt.Printf("Fail getting agreements: %+v\n", err)
+	}
+
+	for id, result := range results {
+		if result.Err != nil {
+			fmt.Printf("%s: fail getting status - %+v\n", id, result.Err)
+			continue
+		}
+		fmt.Printf("%s: %+v\n", id, result.Status)
+	}
+}
@@ -0,0 +1,216 @@
+package gotoontest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Fault is a kind of fault injected by a FaultTransport.
+type Fault string
+
+const (
+	// FaultLatency delays the request.
+	FaultLatency Fault = "latency"
+	// FaultReset fails the request with a connection reset.
+	FaultReset Fault = "reset"
+	// FaultTruncate cuts the response body short.
+	FaultTruncate Fault = "truncate"
+	// FaultAccepted responds with HTTP status 202, i.e. the request is still being
+	// processed, to a storm of subsequent requests.
+	FaultAccepted Fault = "accepted"
+	// FaultUnauthorized rejects the access token with HTTP status 401.
+	FaultUnauthorized Fault = "unauthorized"
+	// FaultMalformed makes the JSON of the response body malformed.
+	FaultMalformed Fault = "malformed"
+)
+
+// Faults are the faults injected by a FaultTransport.  The rates are the
+// probabilities, between 0 and 1, that a request gets the fault.
+//
+// The faults of the responses, i.e. truncated bodies, 202 storms, 401s and
+// malformed JSON, are only injected into the requests of the data endpoints,
+// i.e. with an access token, so that the session itself can be established.
+type Faults struct {
+	// Latency is the maximum delay added to every request; the delay is random.
+	Latency time.Duration
+	// Reset is the rate of requests failed with a connection reset.
+	Reset float64
+	// Truncate is the rate of responses whose body is cut short.
+	Truncate float64
+	// Accepted is the rate of requests starting a storm of responses with HTTP
+	// status 202.
+	Accepted float64
+	// AcceptedStorm is the number of subsequent requests responded with HTTP
+	// status 202 in a storm.  If zero, it is 5.
+	AcceptedStorm int
+	// Unauthorized is the rate of requests rejected with HTTP status 401.
+	Unauthorized float64
+	// Malformed is the rate of responses whose JSON body is malformed.
+	Malformed float64
+}
+
+// FaultTransport is a http.RoundTripper injecting faults into the requests made
+// with the Transport, e.g. to test the resilience of code using the Toon API:
+//
+//	ft := gotoontest.NewFaultTransport(s.Client().Transport, gotoontest.Faults{Reset: 0.1}, 1)
+//	toon.HTTPClient = ft.Client()
+//
+// The faults are drawn from a random source with the given seed, so that a test
+// injects the same faults in every run.
+type FaultTransport struct {
+	// Faults are the faults to inject; they must not be changed while the
+	// FaultTransport is used.
+	Faults Faults
+	// Transport makes the requests.  If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	storm    int
+	injected map[Fault]int
+}
+
+// NewFaultTransport returns a FaultTransport injecting the faults into the
+// requests made with the transport, drawing the faults with the seed.
+func NewFaultTransport(transport http.RoundTripper, faults Faults, seed int64) *FaultTransport {
+	return &FaultTransport{
+		Faults:    faults,
+		Transport: transport,
+		rand:      rand.New(rand.NewSource(seed)),
+		injected:  make(map[Fault]int),
+	}
+}
+
+// Client returns a HTTP client using the FaultTransport.
+func (f *FaultTransport) Client() *http.Client {
+	return &http.Client{Transport: f}
+}
+
+// Injected returns the number of times the fault has been injected.
+func (f *FaultTransport) Injected(fault Fault) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.injected[fault]
+}
+
+// draw reports whether a fault with the given rate occurs, and counts it.
+func (f *FaultTransport) draw(fault Fault, rate float64) bool {
+	if rate <= 0 || f.rand.Float64() >= rate {
+		return false
+	}
+	f.injected[fault]++
+	return true
+}
+
+// RoundTrip implements http.RoundTripper.
+func (f *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	data := strings.HasPrefix(req.Header.Get("authorization"), "Bearer ")
+
+	f.mu.Lock()
+	var delay time.Duration
+	if f.Faults.Latency > 0 && f.draw(FaultLatency, 1) {
+		delay = time.Duration(f.rand.Int63n(int64(f.Faults.Latency)))
+	}
+	reset := f.draw(FaultReset, f.Faults.Reset)
+	var status int
+	if data && !reset {
+		switch {
+		case f.storm > 0:
+			f.storm--
+			f.injected[FaultAccepted]++
+			status = http.StatusAccepted
+		case f.draw(FaultAccepted, f.Faults.Accepted):
+			f.storm = f.Faults.AcceptedStorm - 1
+			if f.Faults.AcceptedStorm <= 0 {
+				f.storm = 4
+			}
+			status = http.StatusAccepted
+		case f.draw(FaultUnauthorized, f.Faults.Unauthorized):
+			status = http.StatusUnauthorized
+		}
+	}
+	truncate := data && !reset && status == 0 && f.draw(FaultTruncate, f.Faults.Truncate)
+	malformed := data && !reset && status == 0 && !truncate && f.draw(FaultMalformed, f.Faults.Malformed)
+	f.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if reset || status != 0 {
+		if req.Body != nil {
+			io.Copy(ioutil.Discard, req.Body)
+			req.Body.Close()
+		}
+	}
+	if reset {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	}
+	if status != 0 {
+		return newResponse(req, status, fmt.Sprintf(`{"fault":%q}`, http.StatusText(status))), nil
+	}
+
+	t := f.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	res, err := t.RoundTrip(req)
+	if err != nil || !(truncate || malformed) {
+		return res, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	half := body[:len(body)/2]
+	res.ContentLength = -1
+	res.Header.Del("content-length")
+	if truncate {
+		res.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(half), errReader{io.ErrUnexpectedEOF}))
+	} else {
+		// a prefix of a JSON document is not a JSON document
+		res.Body = ioutil.NopCloser(bytes.NewReader(half))
+	}
+	return res, nil
+}
+
+// newResponse returns a JSON response of the request with the status and body.
+func newResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// errReader is a reader failing with the error.
+type errReader struct{ err error }
+
+func (r errReader) Read(p []byte) (int, error) { return 0, r.err }
@@ -0,0 +1,122 @@
+package gotoontest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+	"github.com/hurngchunlee/gotoon/gotoontest"
+)
+
+func TestFaultsResilience(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	ft := gotoontest.NewFaultTransport(s.Client().Transport, gotoontest.Faults{
+		Latency:       time.Millisecond,
+		Reset:         0.1,
+		Truncate:      0.1,
+		Accepted:      0.05,
+		AcceptedStorm: 3,
+		Unauthorized:  0.05,
+	}, 1)
+
+	toon := s.Toon()
+	toon.HTTPClient = ft.Client()
+	toon.RetryPolicy = &gotoon.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond}
+
+	// only a token rejected twice in a call fails it
+	a := gotoon.Agreement{AgreementID: "10000001"}
+	failed := 0
+	for i := 0; i < 100; i++ {
+		var apiErr *gotoon.APIError
+		if _, err := toon.GetStatus(a); err != nil {
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("call %d failed: %+v", i, err)
+			}
+			failed++
+		}
+	}
+	if failed > 2 {
+		t.Errorf("too many failed calls: %d", failed)
+	}
+
+	for _, f := range []gotoontest.Fault{
+		gotoontest.FaultLatency,
+		gotoontest.FaultReset,
+		gotoontest.FaultTruncate,
+		gotoontest.FaultAccepted,
+		gotoontest.FaultUnauthorized,
+	} {
+		if ft.Injected(f) == 0 {
+			t.Errorf("fault %s not injected", f)
+		}
+	}
+}
+
+func TestFaultsAcceptedStorm(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	ft := gotoontest.NewFaultTransport(s.Client().Transport, gotoontest.Faults{Accepted: 1, AcceptedStorm: 100}, 1)
+
+	toon := s.Toon()
+	toon.HTTPClient = ft.Client()
+	toon.RetryPolicy = &gotoon.RetryPolicy{MaxAttempts: 6, InitialBackoff: time.Millisecond}
+
+	// the client gives up waiting for the result after MaxAttempts
+	var apiErr *gotoon.APIError
+	_, err := toon.GetStatus(gotoon.Agreement{AgreementID: "10000001"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusAccepted {
+		t.Errorf("expect accepted error: %+v", err)
+	}
+	if n := ft.Injected(gotoontest.FaultAccepted); n != 6 {
+		t.Errorf("unexpected number of accepted responses: %d", n)
+	}
+}
+
+func TestFaultsUnauthorized(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	ft := gotoontest.NewFaultTransport(s.Client().Transport, gotoontest.Faults{Unauthorized: 1}, 1)
+
+	toon := s.Toon()
+	toon.HTTPClient = ft.Client()
+
+	// the token is renewed once
+	var apiErr *gotoon.APIError
+	_, err := toon.GetStatus(gotoon.Agreement{AgreementID: "10000001"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expect unauthorized error: %+v", err)
+	}
+	if n := s.Requests(gotoon.EndpointToken); n != 2 {
+		t.Errorf("expect token refreshed, token requests: %d", n)
+	}
+}
+
+func TestFaultsMalformed(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	for _, f := range []gotoontest.Faults{{Malformed: 1}, {Truncate: 1}} {
+		ft := gotoontest.NewFaultTransport(s.Client().Transport, f, 1)
+
+		toon := s.Toon()
+		toon.HTTPClient = ft.Client()
+
+		a := gotoon.Agreement{AgreementID: "10000001"}
+		if _, err := toon.GetStatus(a); err == nil {
+			t.Errorf("%+v: expect error", f)
+		}
+
+		var syntaxErr *json.SyntaxError
+		now := time.Now()
+		if _, err := toon.GetGasFlow(a, now.Add(-time.Hour), now); f.Malformed > 0 && !errors.As(err, &syntaxErr) {
+			t.Errorf("%+v: expect syntax error: %+v", f, err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package gotoontest
+
+import (
+	"math"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+)
+
+const (
+	// simStep is the time step of the thermal model.
+	simStep = time.Minute
+	// flowInterval is the interval of the data points of the simulated gas flow.
+	flowInterval = 5 * time.Minute
+	// flowPoints is the number of data points of the simulated gas flow kept, a day.
+	flowPoints = 288
+	// gasEnergy is the energy of a dm³ of natural gas in J.
+	gasEnergy = 35.17e3
+	// hysteresis is the difference in K between the indoor temperature and the
+	// setpoint at which the burner is switched on or off.
+	hysteresis = 0.1
+)
+
+// House is a simple thermal model of a house heated by a gas boiler, which is
+// controlled by the thermostat of a Toon, see Server.Simulate.
+//
+// The indoor temperature follows the heat of the boiler and the heat lost to the
+// outdoor temperature through the insulation.  The boiler modulates proportionally
+// to the difference between the setpoint and the indoor temperature, with a
+// minimum modulation level below which the burner is switched on and off.
+type House struct {
+	// Temperature is the initial indoor temperature in °C.
+	Temperature float64
+	// Outdoor returns the outdoor temperature in °C at the given time, e.g.
+	// ConstantOutdoor or DailyOutdoor.  If nil, it is 10°C.
+	Outdoor func(t time.Time) float64
+	// HeatLoss is the heat lost in W per K of difference between the indoor and the
+	// outdoor temperature, i.e. the lower the better the house is insulated.  If
+	// zero, it is 250 W/K.
+	HeatLoss float64
+	// HeatCapacity is the heat in J needed to warm up the house by 1 K.  If zero,
+	// it is 15 MJ/K.
+	HeatCapacity float64
+	// BoilerPower is the maximum heat of the boiler in W.  If zero, it is 24 kW.
+	BoilerPower float64
+	// MinModulation is the minimum modulation level of the boiler in percent.  If
+	// zero, it is 20%.
+	MinModulation float64
+	// Efficiency is the fraction of the energy of the gas heating the house.  If
+	// zero, it is 0.9.
+	Efficiency float64
+	// GasPrice is the price of a m³ of gas.  If zero, it is €1.40.
+	GasPrice gotoon.Money
+}
+
+// withDefaults returns the House with the defaults for the unset parameters.
+func (h House) withDefaults() House {
+	if h.Outdoor == nil {
+		h.Outdoor = ConstantOutdoor(10)
+	}
+	if h.HeatLoss == 0 {
+		h.HeatLoss = 250
+	}
+	if h.HeatCapacity == 0 {
+		h.HeatCapacity = 15e6
+	}
+	if h.BoilerPower == 0 {
+		h.BoilerPower = 24e3
+	}
+	if h.MinModulation == 0 {
+		h.MinModulation = 20
+	}
+	if h.Efficiency == 0 {
+		h.Efficiency = 0.9
+	}
+	if h.GasPrice == 0 {
+		h.GasPrice = 1.40
+	}
+	return h
+}
+
+// ConstantOutdoor returns an outdoor temperature profile of a constant temperature.
+func ConstantOutdoor(temp float64) func(t time.Time) float64 {
+	return func(t time.Time) float64 { return temp }
+}
+
+// DailyOutdoor returns an outdoor temperature profile following a sine between
+// the min temperature at 4:00 and the max temperature at 16:00 in the time zone
+// of the Toon devices.
+func DailyOutdoor(min, max float64) func(t time.Time) float64 {
+	return func(t time.Time) float64 {
+		t = t.In(deviceLocation())
+		h := float64(t.Hour()) + float64(t.Minute())/60
+		return (min+max)/2 - (max-min)/2*math.Cos((h-4)/12*math.Pi)
+	}
+}
+
+// simulation is the state of a House simulated for an agreement.
+type simulation struct {
+	house House
+	// last is the time up to which the House is simulated.
+	last time.Time
+	// burner and modulation are the state of the boiler.
+	burner     bool
+	modulation float64
+	// flow is the gas volume in dm³ used in the current flow interval.
+	flow float64
+	// meter and day are the gas volumes in dm³ of the meter and used on the day.
+	meter, day float64
+	// dayCost is the cost of the gas used on the day.
+	dayCost float64
+}
+
+// Simulate makes the status and the gas flow of the agreement follow the thermal
+// model of the House from the current time of the Server on.  The setpoint of the
+// thermostat is taken from the status of the agreement, e.g. changed by SetPoint.
+// The House is advanced in steps of a minute whenever a request is made.
+func (s *Server) Simulate(agreementID string, h House) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.fixture.Status[agreementID]
+	sim := &simulation{
+		house: h.withDefaults(),
+		last:  s.now(),
+		meter: float64(status.GasUsage.MeterReading),
+	}
+	s.sims[agreementID] = sim
+	s.fixture.GasFlows[agreementID] = gotoon.FlowData{}
+	sim.update(&status, sim.last)
+	s.fixture.Status[agreementID] = status
+}
+
+// simulate advances the simulated houses up to the given time.
+func (s *Server) simulate(until time.Time) {
+	for id, sim := range s.sims {
+		status := s.fixture.Status[id]
+		flow := s.fixture.GasFlows[id]
+
+		for sim.last.Before(until) {
+			dt := simStep
+			if d := until.Sub(sim.last); d < dt {
+				dt = d
+			}
+
+			next := sim.last.Add(dt)
+			sim.step(status.ThermostatInfo.CurrentSetPoint.Celsius(), dt)
+
+			// a new day
+			if !sameDay(sim.last, next) {
+				sim.day, sim.dayCost = 0, 0
+			}
+
+			// the data point of the completed flow interval
+			if start := next.Truncate(flowInterval); sim.last.Before(start) {
+				flow.Hours = append(flow.Hours, gotoon.FlowDataPoint{
+					Timestamp: gotoon.Time{Time: start.Add(-flowInterval)},
+					Unit:      "m3",
+					Value:     gotoon.Float(sim.flow / 1000),
+				})
+				if n := len(flow.Hours); n > flowPoints {
+					flow.Hours = flow.Hours[n-flowPoints:]
+				}
+				sim.flow = 0
+			}
+
+			sim.last = next
+		}
+
+		sim.update(&status, until)
+		s.fixture.Status[id] = status
+		s.fixture.GasFlows[id] = flow
+	}
+}
+
+// step advances the simulation by dt following the setpoint in °C.
+func (sim *simulation) step(setPoint float64, dt time.Duration) {
+	h := sim.house
+	diff := setPoint - h.Temperature
+
+	// switch the burner with hysteresis; modulate proportionally, 100% at 1 K
+	switch {
+	case !sim.burner && diff > hysteresis:
+		sim.burner = true
+	case sim.burner && diff < -hysteresis:
+		sim.burner = false
+	}
+	sim.modulation = 0
+	if sim.burner {
+		sim.modulation = math.Max(h.MinModulation, math.Min(100, diff*100))
+	}
+
+	heat := h.BoilerPower * sim.modulation / 100
+	loss := h.HeatLoss * (h.Temperature - h.Outdoor(sim.last))
+	sim.house.Temperature += (heat - loss) * dt.Seconds() / h.HeatCapacity
+
+	gas := heat * dt.Seconds() / (h.Efficiency * gasEnergy)
+	sim.flow += gas
+	sim.meter += gas
+	sim.day += gas
+	sim.dayCost += gas / 1000 * float64(h.GasPrice)
+}
+
+// update sets the status following the simulation at the given time.
+func (sim *simulation) update(status *gotoon.Status, now time.Time) {
+	h := sim.house
+	updated := gotoon.Time{Time: now}
+
+	info := &status.ThermostatInfo
+	info.CurrentDisplayTemp = gotoon.TemperatureFromCelsius(h.Temperature)
+	info.BurnerInfo = "0"
+	if sim.burner {
+		info.BurnerInfo = "1"
+	}
+	info.CurrentModulationLevel = gotoon.Int(math.Round(sim.modulation))
+	info.LastUpdatedFromDisplay = updated
+
+	// the current gas flow in dm³ per hour
+	gas := &status.GasUsage
+	gas.Value = gotoon.GasFlow(math.Round(h.BoilerPower * sim.modulation / 100 * 3600 / (h.Efficiency * gasEnergy)))
+	gas.MeterReading = gotoon.Volume(math.Round(sim.meter))
+	gas.DayUsage = gotoon.Volume(math.Round(sim.day))
+	gas.DayCost = gotoon.Money(sim.dayCost)
+	gas.LastUpdatedFromDisplay = updated
+
+	status.LastUpdateFromDisplay = updated
+}
+
+// sameDay reports whether the times are on the same day in the time zone of the
+// Toon devices.
+func sameDay(t1, t2 time.Time) bool {
+	y1, m1, d1 := t1.In(deviceLocation()).Date()
+	y2, m2, d2 := t2.In(deviceLocation()).Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// deviceLocation returns the time zone of the Toon devices, or UTC if it is unknown.
+func deviceLocation() *time.Location {
+	if loc, err := gotoon.DeviceLocation(); err == nil {
+		return loc
+	}
+	return time.UTC
+}
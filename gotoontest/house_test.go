@@ -0,0 +1,95 @@
+package gotoontest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+	"github.com/hurngchunlee/gotoon/gotoontest"
+)
+
+func TestHouseSimulation(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	start := time.Date(2024, 1, 15, 5, 0, 0, 0, time.UTC)
+	clock := gotoontest.NewClock(start)
+	s.Clock = clock
+
+	const id = "10000001"
+	s.UpdateStatus(id, func(status *gotoon.Status) {
+		status.ThermostatInfo.CurrentSetPoint = 2000
+	})
+	s.Simulate(id, gotoontest.House{Temperature: 15, Outdoor: gotoontest.ConstantOutdoor(5)})
+
+	// lower the setpoint after the warming up
+	s.Play(gotoontest.Step{At: 7 * time.Hour, Do: gotoontest.SetPoint(id, 1600)})
+
+	toon := s.Toon()
+	a := gotoon.Agreement{AgreementID: id}
+
+	var max gotoon.Temperature
+	var on, off int
+	for i := 0; i < 36; i++ {
+		clock.Advance(10 * time.Minute)
+
+		status, err := toon.GetStatus(a)
+		if err != nil {
+			t.Fatalf("fail getting status: %+v", err)
+		}
+
+		info := status.ThermostatInfo
+		if i >= 18 {
+			// the burner cycles to keep the setpoint
+			if info.BurnerInfo == "1" {
+				on++
+			} else {
+				off++
+			}
+		}
+		if info.CurrentDisplayTemp > max {
+			max = info.CurrentDisplayTemp
+		}
+		if i == 35 && (info.CurrentDisplayTemp.Celsius() < 19.5 || info.CurrentDisplayTemp.Celsius() > 20.5) {
+			t.Errorf("setpoint not reached: %s", info.CurrentDisplayTemp)
+		}
+	}
+	if max.Celsius() > 20.5 {
+		t.Errorf("overshoot of the setpoint: %s", max)
+	}
+	if on == 0 || off == 0 {
+		t.Errorf("burner not cycling: %d on, %d off", on, off)
+	}
+
+	// the gas used in the last hour
+	now := clock.Now()
+	flow, err := toon.GetGasFlow(a, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("fail getting gas flow: %+v", err)
+	}
+	var used float64
+	for _, p := range flow.Hours {
+		v, _ := p.Volume()
+		used += v.CubicMeters()
+	}
+	if n := len(flow.Hours); n != 12 || used <= 0 {
+		t.Errorf("unexpected gas flow: %d data points, %.3f m³", n, used)
+	}
+
+	// after lowering the setpoint the house cools down without heating
+	clock.Advance(2 * time.Hour)
+	status, err := toon.GetStatus(a)
+	if err != nil {
+		t.Fatalf("fail getting status: %+v", err)
+	}
+	info := status.ThermostatInfo
+	if info.BurnerInfo != "0" || info.CurrentModulationLevel != 0 || status.GasUsage.Value != 0 {
+		t.Errorf("burner not off: %+v", info)
+	}
+	if c := info.CurrentDisplayTemp.Celsius(); c >= 20 || c <= 16 {
+		t.Errorf("unexpected temperature after lowering the setpoint: %.2f", c)
+	}
+	if status.GasUsage.DayUsage <= 0 || status.GasUsage.DayCost <= 0 {
+		t.Errorf("no gas used on the day: %+v", status.GasUsage)
+	}
+}
@@ -0,0 +1,119 @@
+package gotoontest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+)
+
+// Call is a call made to a Mock.
+type Call struct {
+	// Method is the name of the called method, e.g. "GetStatus".
+	Method string
+	// Args are the arguments of the call.
+	Args []interface{}
+}
+
+// Mock is a gotoon.ToonAPI recording its calls, for testing code using the Toon
+// API without a server.  A method returns the result of the corresponding
+// function, e.g. GetStatusFunc for GetStatus, or zero values if it is nil.
+//
+// The functions must be set before the Mock is used; the Mock is safe for
+// concurrent use.
+type Mock struct {
+	GetAgreementsFunc func() ([]gotoon.Agreement, error)
+	GetStatusFunc     func(agreement gotoon.Agreement) (gotoon.Status, error)
+	GetStatusAllFunc  func(ctx context.Context) (map[string]gotoon.StatusResult, error)
+	GetGasFlowFunc    func(agreement gotoon.Agreement, fromTime, toTime time.Time) (gotoon.FlowData, error)
+	TokenInfoFunc     func() gotoon.TokenInfo
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ gotoon.ToonAPI = (*Mock)(nil)
+
+// Calls returns the calls made to the Mock in order.  If methods are given, only
+// the calls of those methods are returned.
+func (m *Mock) Calls(methods ...string) (calls []Call) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.calls {
+		if len(methods) == 0 || contains(methods, c.Method) {
+			calls = append(calls, c)
+		}
+	}
+	return
+}
+
+// Reset forgets the calls made to the Mock.
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = nil
+}
+
+func (m *Mock) record(method string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+// GetAgreements implements gotoon.DevicesAPI.
+func (m *Mock) GetAgreements() ([]gotoon.Agreement, error) {
+	m.record("GetAgreements")
+	if m.GetAgreementsFunc == nil {
+		return nil, nil
+	}
+	return m.GetAgreementsFunc()
+}
+
+// GetStatus implements gotoon.ThermostatAPI.
+func (m *Mock) GetStatus(agreement gotoon.Agreement) (gotoon.Status, error) {
+	m.record("GetStatus", agreement)
+	if m.GetStatusFunc == nil {
+		return gotoon.Status{}, nil
+	}
+	return m.GetStatusFunc(agreement)
+}
+
+// GetStatusAll implements gotoon.ThermostatAPI.
+func (m *Mock) GetStatusAll(ctx context.Context) (map[string]gotoon.StatusResult, error) {
+	m.record("GetStatusAll", ctx)
+	if m.GetStatusAllFunc == nil {
+		return nil, nil
+	}
+	return m.GetStatusAllFunc(ctx)
+}
+
+// GetGasFlow implements gotoon.ConsumptionAPI.
+func (m *Mock) GetGasFlow(agreement gotoon.Agreement, fromTime, toTime time.Time) (gotoon.FlowData, error) {
+	m.record("GetGasFlow", agreement, fromTime, toTime)
+	if m.GetGasFlowFunc == nil {
+		return gotoon.FlowData{}, nil
+	}
+	return m.GetGasFlowFunc(agreement, fromTime, toTime)
+}
+
+// TokenInfo implements gotoon.ToonAPI.
+func (m *Mock) TokenInfo() gotoon.TokenInfo {
+	m.record("TokenInfo")
+	if m.TokenInfoFunc == nil {
+		return gotoon.TokenInfo{}
+	}
+	return m.TokenInfoFunc()
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package gotoontest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+	"github.com/hurngchunlee/gotoon/gotoontest"
+)
+
+// coldest returns the agreement of the coldest Toon, as code using the Toon API
+// would do.
+func coldest(api gotoon.ToonAPI) (coldest gotoon.Agreement, err error) {
+	agreements, err := api.GetAgreements()
+	if err != nil {
+		return
+	}
+	min := gotoon.Temperature(1 << 30)
+	for _, a := range agreements {
+		status, err := api.GetStatus(a)
+		if err != nil {
+			continue
+		}
+		if t := status.ThermostatInfo.CurrentDisplayTemp; t < min {
+			min, coldest = t, a
+		}
+	}
+	return
+}
+
+func TestMock(t *testing.T) {
+	temps := map[string]gotoon.Temperature{"1": 2000, "2": 1650, "3": 1800}
+
+	m := &gotoontest.Mock{
+		GetAgreementsFunc: func() ([]gotoon.Agreement, error) {
+			return []gotoon.Agreement{{AgreementID: "1"}, {AgreementID: "2"}, {AgreementID: "3"}, {AgreementID: "4"}}, nil
+		},
+		GetStatusFunc: func(a gotoon.Agreement) (s gotoon.Status, err error) {
+			t, ok := temps[a.AgreementID]
+			if !ok {
+				return s, errors.New("offline")
+			}
+			s.ThermostatInfo.CurrentDisplayTemp = t
+			return
+		},
+	}
+
+	a, err := coldest(m)
+	if err != nil || a.AgreementID != "2" {
+		t.Errorf("unexpected coldest agreement %+v: %v", a, err)
+	}
+
+	if n := len(m.Calls()); n != 5 {
+		t.Errorf("unexpected number of calls: %d", n)
+	}
+	calls := m.Calls("GetStatus")
+	if len(calls) != 4 || calls[3].Args[0].(gotoon.Agreement).AgreementID != "4" {
+		t.Errorf("unexpected GetStatus calls: %+v", calls)
+	}
+
+	m.Reset()
+	if _, err := m.GetGasFlow(a, time.Time{}, time.Now()); err != nil || len(m.Calls()) != 1 {
+		t.Errorf("unexpected calls after reset: %+v", m.Calls())
+	}
+}
@@ -0,0 +1,325 @@
+package gotoontest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hurngchunlee/gotoon"
+)
+
+// accountParams are the names of the request parameters and JSON fields which,
+// besides the secrets redacted by gotoon, identify the account in a Cassette.
+var accountParams = []string{"username", "client_id", "agreementIdChecksum"}
+
+// agreementIDPath matches the numeric agreement identifiers in URL paths.
+var agreementIDPath = regexp.MustCompile(`^[0-9]{6,}$`)
+
+// Mode is the mode of a Recorder.
+type Mode int
+
+const (
+	// ModeReplay serves the interactions of the Cassette without making requests.
+	ModeReplay Mode = iota
+	// ModeRecord makes the requests and records the interactions in the Cassette.
+	ModeRecord
+)
+
+// Interaction is a recorded HTTP request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded HTTP request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded HTTP response.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Cassette is a sequence of recorded interactions, stored as a JSON file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads the Cassette from the file.
+func LoadCassette(path string) (c *Cassette, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	c = &Cassette{}
+	err = json.Unmarshal(b, c)
+	return
+}
+
+// Save writes the Cassette to the file.
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Recorder is a http.RoundTripper recording the exchanges with the Toon API to a
+// Cassette, or replaying them from it, e.g. to reproduce a captured session in a
+// test:
+//
+//	rec, err := gotoontest.NewRecorder("testdata/session.json", gotoontest.ModeReplay)
+//	toon.HTTPClient = rec.Client()
+//
+// Recorded interactions are redacted: the credentials and tokens are replaced by
+// REDACTED, and the agreement identifiers, i.e. the agreementId JSON fields and the
+// numeric URL path segments, by placeholders, consistently across the Cassette, so
+// that the replayed session stays coherent.
+//
+// A request is replayed with the first unused interaction of the same method and
+// URL path; the query, e.g. the time range of a flow, and the body are ignored.
+type Recorder struct {
+	// Mode is the mode of the Recorder.
+	Mode Mode
+	// Cassette holds the recorded interactions.
+	Cassette *Cassette
+	// Transport makes the requests in ModeRecord.  If nil, http.DefaultTransport
+	// is used.
+	Transport http.RoundTripper
+
+	mu sync.Mutex
+	// used marks the replayed interactions.
+	used map[int]bool
+	// ids maps the agreement identifiers to their placeholders.
+	ids map[string]string
+	// path is the file of the Cassette.
+	path string
+}
+
+// NewRecorder returns a Recorder of the Cassette in the file.  In ModeReplay the
+// Cassette is loaded from the file; in ModeRecord it is written by Save.
+func NewRecorder(path string, mode Mode) (r *Recorder, err error) {
+	r = &Recorder{Mode: mode, Cassette: &Cassette{}, path: path}
+	if mode == ModeReplay {
+		r.Cassette, err = LoadCassette(path)
+	}
+	return
+}
+
+// Client returns a HTTP client using the Recorder.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Save writes the Cassette to the file of the Recorder.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.Cassette.Save(r.path)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.Mode == ModeReplay {
+		return r.replay(req)
+	}
+	return r.record(req)
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(ioutil.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.used == nil {
+		r.used = make(map[int]bool)
+	}
+
+	for i, in := range r.Cassette.Interactions {
+		u, err := url.Parse(in.Request.URL)
+		if err != nil || r.used[i] || in.Request.Method != req.Method || u.Path != req.URL.Path {
+			continue
+		}
+		r.used[i] = true
+
+		res := newResponse(req, in.Response.StatusCode, in.Response.Body)
+		res.Header = in.Response.Header.Clone()
+		return res, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL.Path)
+}
+
+func (r *Recorder) record(req *http.Request) (res *http.Response, err error) {
+
+	var reqBody []byte
+	if req.Body != nil {
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	if res, err = t.RoundTrip(req); err != nil {
+		return
+	}
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Cassette.Interactions = append(r.Cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    r.redactURL(req.URL),
+			Header: r.redactHeader(req.Header),
+			Body:   r.redactBody(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     r.redactHeader(res.Header),
+			Body:       r.redactBody(resBody),
+		},
+	})
+	return
+}
+
+// redactID returns the placeholder of the agreement identifier id.
+func (r *Recorder) redactID(id string) string {
+	if r.ids == nil {
+		r.ids = make(map[string]string)
+	}
+	p, ok := r.ids[id]
+	if !ok {
+		p = fmt.Sprintf("agreement-%d", len(r.ids)+1)
+		r.ids[id] = p
+	}
+	return p
+}
+
+// redactURL returns the URL with the agreement identifiers in the path and the
+// secret query parameters redacted.
+func (r *Recorder) redactURL(u *url.URL) string {
+	c := *u
+	segs := strings.Split(c.Path, "/")
+	for i, seg := range segs {
+		if agreementIDPath.MatchString(seg) {
+			segs[i] = r.redactID(seg)
+		}
+	}
+	c.Path = strings.Join(segs, "/")
+	c.RawPath = ""
+	c.RawQuery = gotoon.RedactValues(c.Query(), accountParams...).Encode()
+	return c.String()
+}
+
+// redactHeader returns a copy of the header with the authorization, the cookies
+// and the redirect location redacted.
+func (r *Recorder) redactHeader(h http.Header) http.Header {
+	c := h.Clone()
+	for k := range c {
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization":
+			c.Set(k, "Bearer "+gotoon.Redacted)
+		case "Cookie", "Set-Cookie":
+			c.Set(k, gotoon.Redacted)
+		case "Location":
+			if u, err := url.Parse(c.Get(k)); err == nil {
+				c.Set(k, r.redactURL(u))
+			}
+		}
+	}
+	return c
+}
+
+// redactBody returns the request or response body with the secret form parameters
+// and JSON fields, and the agreementId JSON fields redacted.
+func (r *Recorder) redactBody(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+
+	// keep the numbers as they are, e.g. timestamps and meter readings
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if d.Decode(&v) == nil {
+		if r.redactJSON(v) {
+			b, _ = json.Marshal(v)
+		}
+		return string(b)
+	}
+
+	if form, err := url.ParseQuery(string(b)); err == nil {
+		return gotoon.RedactValues(form, accountParams...).Encode()
+	}
+	return string(b)
+}
+
+// isSecret reports whether the request parameter or JSON field name is redacted.
+func isSecret(name string) bool {
+	if gotoon.IsSecretParam(name) {
+		return true
+	}
+	for _, p := range accountParams {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// redactJSON redacts the secret fields and the agreementId fields of the decoded
+// JSON value in place, and reports whether any field is redacted.
+func (r *Recorder) redactJSON(v interface{}) (changed bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if isSecret(k) {
+				v[k] = gotoon.Redacted
+				changed = true
+				continue
+			}
+			if id, ok := e.(string); ok && k == "agreementId" && id != "" {
+				v[k] = r.redactID(id)
+				changed = true
+				continue
+			}
+			changed = r.redactJSON(e) || changed
+		}
+	case []interface{}:
+		for _, e := range v {
+			changed = r.redactJSON(e) || changed
+		}
+	}
+	return
+}
@@ -0,0 +1,155 @@
+package gotoontest_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+	"github.com/hurngchunlee/gotoon/gotoontest"
+)
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	// record a session with the fake server
+	s := gotoontest.NewServer()
+
+	rec, err := gotoontest.NewRecorder(path, gotoontest.ModeRecord)
+	if err != nil {
+		t.Fatalf("fail creating recorder: %+v", err)
+	}
+	rec.Transport = s.Client().Transport
+
+	toon := s.Toon()
+	toon.HTTPClient = rec.Client()
+
+	now := time.Now()
+	agreements, err := toon.GetAgreements()
+	if err != nil {
+		t.Fatalf("fail getting agreements: %+v", err)
+	}
+	status, err := toon.GetStatus(agreements[0])
+	if err != nil {
+		t.Fatalf("fail getting status: %+v", err)
+	}
+	flow, err := toon.GetGasFlow(agreements[0], now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("fail getting gas flow: %+v", err)
+	}
+	s.Close()
+
+	if err := rec.Save(); err != nil {
+		t.Fatalf("fail saving cassette: %+v", err)
+	}
+
+	// the cassette holds no secrets
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fail reading cassette: %+v", err)
+	}
+	for _, secret := range []string{
+		gotoontest.Username,
+		gotoontest.Password,
+		gotoontest.ConsumerKey,
+		gotoontest.ConsumerSecret,
+		agreements[0].AgreementID,
+		agreements[0].AgreementIDChecksum,
+	} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains secret %q", secret)
+		}
+	}
+	if n := strings.Count(string(b), `\"access_token\":\"REDACTED\"`); n != 1 {
+		t.Errorf("access token not redacted in token response: %d", n)
+	}
+
+	// replay the session without the server nor the credentials
+	rep, err := gotoontest.NewRecorder(path, gotoontest.ModeReplay)
+	if err != nil {
+		t.Fatalf("fail loading cassette: %+v", err)
+	}
+	replayed := &gotoon.Toon{
+		TenantID:       "eneco",
+		ConsumerKey:    "key",
+		ConsumerSecret: "secret",
+		Username:       "user",
+		Password:       "password",
+		Endpoints:      s.Endpoints(),
+		HTTPClient:     rep.Client(),
+	}
+
+	agreements2, err := replayed.GetAgreements()
+	if err != nil {
+		t.Fatalf("fail replaying agreements: %+v", err)
+	}
+	if len(agreements2) != 1 || agreements2[0].AgreementID != "agreement-1" {
+		t.Fatalf("unexpected replayed agreements: %+v", agreements2)
+	}
+	status2, err := replayed.GetStatus(agreements2[0])
+	if err != nil {
+		t.Fatalf("fail replaying status: %+v", err)
+	}
+	if status2.ThermostatInfo.CurrentSetPoint != status.ThermostatInfo.CurrentSetPoint ||
+		!status2.LastUpdateFromDisplay.Equal(status.LastUpdateFromDisplay.Time) {
+		t.Errorf("unexpected replayed status: %+v", status2)
+	}
+	flow2, err := replayed.GetGasFlow(agreements2[0], now.Add(-2*time.Hour), now)
+	if err != nil {
+		t.Fatalf("fail replaying gas flow: %+v", err)
+	}
+	if len(flow2.Hours) != len(flow.Hours) {
+		t.Errorf("unexpected replayed gas flow: %+v", flow2)
+	}
+
+	// the interactions are replayed only once
+	if _, err := replayed.GetAgreements(); err == nil {
+		t.Errorf("expect no interaction left for agreements")
+	}
+}
+
+// transportFunc is a http.RoundTripper of a function.
+type transportFunc func(req *http.Request) (*http.Response, error)
+
+func (f transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRecorderRedactsOnlyIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	rec, err := gotoontest.NewRecorder(path, gotoontest.ModeRecord)
+	if err != nil {
+		t.Fatalf("fail creating recorder: %+v", err)
+	}
+
+	// the agreement identifier appears in a timestamp and a meter reading as well
+	body := `{"agreementId":"123456","lastUpdateFromDisplay":1712345678901,"meterReading":123456.5,"meterReadingHigh":11234567}`
+	rec.Transport = transportFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+
+	res, err := rec.Client().Get("http://toon.test/toon/v3/123456/status?from=1234567")
+	if err != nil {
+		t.Fatalf("fail recording: %+v", err)
+	}
+	res.Body.Close()
+
+	in := rec.Cassette.Interactions[0]
+	if in.Request.URL != "http://toon.test/toon/v3/agreement-1/status?from=1234567" {
+		t.Errorf("unexpected redacted URL: %s", in.Request.URL)
+	}
+	for _, s := range []string{`"agreementId":"agreement-1"`, "1712345678901", "123456.5", "11234567"} {
+		if !strings.Contains(in.Response.Body, s) {
+			t.Errorf("redacted body lacks %s: %s", s, in.Response.Body)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package gotoontest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+)
+
+// Clock is a fake clock for the Server and its clients, which only moves when
+// it is advanced.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock starting at the given time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current time of the Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the Clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Set moves the Clock to the given time.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// Step is a change of the Server at a time of a scenario, see Server.Play.
+type Step struct {
+	// At is the time of the Step relative to the start of the scenario.
+	At time.Duration
+	// Do changes the Server, e.g. with SetPoint, Burner or Fail.
+	Do func(s *Server)
+}
+
+// step is a Step scheduled at an absolute time.
+type step struct {
+	at time.Time
+	do func(s *Server)
+}
+
+// Play schedules the steps of a scenario starting at the current time of the
+// Server.  A Step is run before the first request made at or after its time, in
+// the order of the times of the steps.
+func (s *Server) Play(steps ...Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := s.now()
+	for _, st := range steps {
+		s.steps = append(s.steps, step{at: start.Add(st.At), do: st.Do})
+	}
+	sort.SliceStable(s.steps, func(i, j int) bool {
+		return s.steps[i].at.Before(s.steps[j].at)
+	})
+}
+
+// runSteps runs the steps of the scenarios which are due, and advances the
+// simulated houses up to the time of each step and the current time.
+func (s *Server) runSteps() {
+	for {
+		s.mu.Lock()
+		now := s.now()
+		if len(s.steps) == 0 || s.steps[0].at.After(now) {
+			s.simulate(now)
+			s.mu.Unlock()
+			return
+		}
+		st := s.steps[0]
+		s.steps = s.steps[1:]
+		s.simulate(st.at)
+		s.mu.Unlock()
+
+		st.do(s)
+	}
+}
+
+// SetPoint returns the action of a Step changing the setpoint of the thermostat
+// of the agreement.
+func SetPoint(agreementID string, temp gotoon.Temperature) func(s *Server) {
+	return func(s *Server) {
+		s.UpdateStatus(agreementID, func(status *gotoon.Status) {
+			status.ThermostatInfo.CurrentSetPoint = temp
+			status.ThermostatInfo.RealSetPoint = temp
+		})
+	}
+}
+
+// Burner returns the action of a Step switching the burner of the boiler of the
+// agreement on, with the modulation level in percent, or off.
+func Burner(agreementID string, on bool, modulation int) func(s *Server) {
+	return func(s *Server) {
+		s.UpdateStatus(agreementID, func(status *gotoon.Status) {
+			status.ThermostatInfo.BurnerInfo = "0"
+			status.ThermostatInfo.CurrentModulationLevel = 0
+			if on {
+				status.ThermostatInfo.BurnerInfo = "1"
+				status.ThermostatInfo.CurrentModulationLevel = gotoon.Int(modulation)
+			}
+		})
+	}
+}
+
+// Fail returns the action of a Step making the next n calls of the endpoint
+// respond with the HTTP status, see Server.FailNext.
+func Fail(endpoint string, status, n int) func(s *Server) {
+	return func(s *Server) {
+		s.FailNext(endpoint, status, n)
+	}
+}
@@ -0,0 +1,118 @@
+package gotoontest_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+	"github.com/hurngchunlee/gotoon/gotoontest"
+)
+
+func TestScenarioTokenExpiry(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	clock := gotoontest.NewClock(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	s.Clock = clock
+
+	var events []gotoon.TokenEventType
+	toon := s.Toon()
+	toon.OnTokenEvent = func(e gotoon.TokenEvent) { events = append(events, e.Type) }
+
+	a := gotoon.Agreement{AgreementID: "10000001"}
+	get := func(when string) {
+		if _, err := toon.GetStatus(a); err != nil {
+			t.Fatalf("%s: fail getting status: %+v", when, err)
+		}
+	}
+
+	get("login")
+
+	// the access token expires 3 minutes ahead of its lifetime of 30 minutes
+	clock.Advance(26 * time.Minute)
+	get("valid token")
+	clock.Advance(2 * time.Minute)
+	get("expired token")
+
+	// a rejected refresh falls back to a login
+	clock.Advance(28 * time.Minute)
+	s.ExpireTokens(true)
+	get("revoked refresh token")
+
+	// the refresh token expires after a day
+	clock.Advance(25 * time.Hour)
+	get("expired refresh token")
+
+	expect := []gotoon.TokenEventType{
+		gotoon.TokenLogin,
+		gotoon.TokenRefresh,
+		gotoon.TokenRefreshFailed,
+		gotoon.TokenLogin,
+		gotoon.TokenRefreshExpired,
+		gotoon.TokenLogin,
+	}
+	if !reflect.DeepEqual(events, expect) {
+		t.Errorf("unexpected token events: %v", events)
+	}
+
+	if n := s.Requests(gotoon.EndpointAuthorize); n != 3 {
+		t.Errorf("unexpected number of logins: %d", n)
+	}
+}
+
+func TestScenarioStateChanges(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	clock := gotoontest.NewClock(time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC))
+	s.Clock = clock
+
+	const id = "10000001"
+	s.Play(
+		gotoontest.Step{At: 10 * time.Minute, Do: gotoontest.SetPoint(id, 2150)},
+		gotoontest.Step{At: 20 * time.Minute, Do: gotoontest.Burner(id, false, 0)},
+		gotoontest.Step{At: 15 * time.Minute, Do: gotoontest.Burner(id, true, 80)},
+		gotoontest.Step{At: 30 * time.Minute, Do: gotoontest.Fail(gotoon.EndpointStatus, http.StatusInternalServerError, 2)},
+	)
+
+	toon := s.Toon()
+	a := gotoon.Agreement{AgreementID: id}
+
+	for _, c := range []struct {
+		at         time.Duration
+		setPoint   gotoon.Temperature
+		burner     string
+		modulation gotoon.Int
+	}{
+		{at: 5 * time.Minute, setPoint: 2000, burner: "1", modulation: 35},
+		{at: 12 * time.Minute, setPoint: 2150, burner: "1", modulation: 35},
+		{at: 17 * time.Minute, setPoint: 2150, burner: "1", modulation: 80},
+		{at: 25 * time.Minute, setPoint: 2150, burner: "0", modulation: 0},
+	} {
+		clock.Set(time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC).Add(c.at))
+
+		status, err := toon.GetStatus(a)
+		if err != nil {
+			t.Fatalf("%s: fail getting status: %+v", c.at, err)
+		}
+		info := status.ThermostatInfo
+		if info.CurrentSetPoint != c.setPoint || info.BurnerInfo != c.burner || info.CurrentModulationLevel != c.modulation {
+			t.Errorf("%s: unexpected thermostat: setpoint %s, burner %s, modulation %d", c.at, info.CurrentSetPoint, info.BurnerInfo, info.CurrentModulationLevel)
+		}
+	}
+
+	// the next two calls fail after 30 minutes
+	clock.Advance(10 * time.Minute)
+	var apiErr *gotoon.APIError
+	for i := 0; i < 2; i++ {
+		if _, err := toon.GetStatus(a); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("expect internal server error: %+v", err)
+		}
+	}
+	if _, err := toon.GetStatus(a); err != nil {
+		t.Errorf("fail getting status after the failures: %+v", err)
+	}
+}
@@ -0,0 +1,496 @@
+// Package gotoontest provides an in-process fake of the Toon API for testing
+// code using the gotoon library without real Toon credentials.
+//
+// The Server implements the authorization, token and data endpoints used by
+// gotoon.Toon, serves seedable fixtures and injects errors on demand:
+//
+//	s := gotoontest.NewServer()
+//	defer s.Close()
+//
+//	toon := s.Toon()
+//	agreements, err := toon.GetAgreements()
+//
+// Time dependent behaviour, e.g. the expiry of the tokens, is tested with a fake
+// Clock shared by the Server and its clients, and scenarios of Steps changing the
+// state of the Server at given times.
+package gotoontest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+)
+
+// Credentials accepted by a new Server.
+const (
+	Username       = "toon-user"
+	Password       = "toon-password"
+	TenantID       = "eneco"
+	ConsumerKey    = "toon-consumer-key"
+	ConsumerSecret = "toon-consumer-secret"
+)
+
+// Fixture holds the data served by the Server.
+type Fixture struct {
+	Agreements []gotoon.Agreement
+	// Status is the device status by AgreementID.
+	Status map[string]gotoon.Status
+	// GasFlows is the gas consumption by AgreementID.
+	GasFlows map[string]gotoon.FlowData
+}
+
+// Server is a fake Toon API server.
+type Server struct {
+	*httptest.Server
+
+	// TokenTTL and RefreshTokenTTL are the lifetimes of the issued access and
+	// refresh tokens.
+	TokenTTL        time.Duration
+	RefreshTokenTTL time.Duration
+	// Clock is the clock of the Server and the clients made by Toon.  If nil, the
+	// real time is used.
+	Clock *Clock
+
+	mu       sync.Mutex
+	fixture  Fixture
+	codes    map[string]bool
+	access   map[string]time.Time
+	refresh  map[string]time.Time
+	faults   []fault
+	requests map[string]int
+	steps    []step
+	sims     map[string]*simulation
+}
+
+// fault is an error injected into the next calls of an endpoint.
+type fault struct {
+	endpoint string
+	status   int
+	n        int
+}
+
+// NewServer starts a Server seeded with the DefaultFixture.  The caller must
+// Close the Server when done.
+func NewServer() *Server {
+	s := &Server{
+		TokenTTL:        30 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+		codes:           make(map[string]bool),
+		access:          make(map[string]time.Time),
+		refresh:         make(map[string]time.Time),
+		requests:        make(map[string]int),
+		sims:            make(map[string]*simulation),
+	}
+	s.Seed(DefaultFixture())
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// DefaultFixture returns a Fixture of one agreement with a gas heated Toon.
+func DefaultFixture() Fixture {
+	now := time.Now().Truncate(time.Millisecond)
+	updated := gotoon.Time{Time: now}
+
+	var hours []gotoon.FlowDataPoint
+	for i := 12; i > 0; i-- {
+		hours = append(hours, gotoon.FlowDataPoint{
+			Timestamp: gotoon.Time{Time: now.Add(-time.Duration(i) * 5 * time.Minute)},
+			Unit:      "m3",
+			Value:     0.015,
+		})
+	}
+
+	return Fixture{
+		Agreements: []gotoon.Agreement{{
+			AgreementID:            "10000001",
+			AgreementIDChecksum:    "e1b2c3d4",
+			HeatingType:            gotoon.HeatingGas,
+			DisplayCommonName:      "eneco-001-000001",
+			DisplayHardwareVersion: "qb2/ene/2.10.4",
+			DisplaySoftwareVersion: "qb2/ene/4.19.10",
+		}},
+		Status: map[string]gotoon.Status{
+			"10000001": {
+				ThermostatStates: gotoon.ThermostatStates{
+					State: []gotoon.ThermostatState{
+						{ID: gotoon.StateComfort, TempValue: 2000},
+						{ID: gotoon.StateHome, TempValue: 1800},
+						{ID: gotoon.StateSleep, TempValue: 1500},
+						{ID: gotoon.StateAway, TempValue: 1200},
+					},
+					LastUpdatedFromDisplay: updated,
+				},
+				ThermostatInfo: gotoon.ThermostatInfo{
+					CurrentSetPoint:        2000,
+					CurrentDisplayTemp:     1950,
+					ProgramState:           gotoon.ProgramOn,
+					ActiveState:            gotoon.StateComfort,
+					NextProgram:            gotoon.ProgramOn,
+					NextState:              gotoon.StateSleep,
+					NextSetPoint:           1500,
+					BoilerModuleConnected:  1,
+					RealSetPoint:           2000,
+					BurnerInfo:             "1",
+					OtCommError:            "0",
+					CurrentModulationLevel: 35,
+					HaveOTBoiler:           1,
+					LastUpdatedFromDisplay: updated,
+				},
+				PowerUsage: gotoon.PowerUsage{
+					Value:                  420,
+					DayCost:                1.25,
+					AvgValue:               380.5,
+					MeterReading:           12345678,
+					MeterReadingLow:        8765432,
+					DayUsage:               5400,
+					IsSmart:                true,
+					LastUpdatedFromDisplay: updated,
+				},
+				GasUsage: gotoon.GasUsage{
+					Value:                  180,
+					DayCost:                2.10,
+					AvgValue:               150.2,
+					MeterReading:           4567890,
+					DayUsage:               2800,
+					IsSmart:                true,
+					LastUpdatedFromDisplay: updated,
+				},
+				LastUpdateFromDisplay: updated,
+			},
+		},
+		GasFlows: map[string]gotoon.FlowData{
+			"10000001": {Hours: hours},
+		},
+	}
+}
+
+// Toon returns a new Toon client of the Server, with valid credentials.
+func (s *Server) Toon() *gotoon.Toon {
+	var clock func() time.Time
+	if s.Clock != nil {
+		clock = s.Clock.Now
+	}
+
+	return &gotoon.Toon{
+		Username:       Username,
+		Password:       Password,
+		TenantID:       TenantID,
+		ConsumerKey:    ConsumerKey,
+		ConsumerSecret: ConsumerSecret,
+		Endpoints:      s.Endpoints(),
+		HTTPClient:     s.Client(),
+		Clock:          clock,
+	}
+}
+
+// Endpoints returns the Endpoints of the Server.
+func (s *Server) Endpoints() gotoon.Endpoints {
+	return gotoon.Endpoints{
+		AuthorizeURL: s.URL + "/authorize",
+		TokenURL:     s.URL + "/token",
+		APIBaseURL:   s.URL + "/toon/v3",
+	}
+}
+
+// Seed replaces the data served by the Server.
+func (s *Server) Seed(f Fixture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.Status == nil {
+		f.Status = make(map[string]gotoon.Status)
+	}
+	if f.GasFlows == nil {
+		f.GasFlows = make(map[string]gotoon.FlowData)
+	}
+	s.fixture = f
+}
+
+// SetStatus sets the status served for the agreement.
+func (s *Server) SetStatus(agreementID string, status gotoon.Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixture.Status[agreementID] = status
+}
+
+// UpdateStatus changes the status served for the agreement with the function f.
+func (s *Server) UpdateStatus(agreementID string, f func(status *gotoon.Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.fixture.Status[agreementID]
+	f(&status)
+	s.fixture.Status[agreementID] = status
+}
+
+// SetGasFlow sets the gas consumption served for the agreement.
+func (s *Server) SetGasFlow(agreementID string, flow gotoon.FlowData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixture.GasFlows[agreementID] = flow
+}
+
+// FailNext makes the next n calls of the endpoint respond with the HTTP status,
+// e.g. http.StatusAccepted, http.StatusUnauthorized or http.StatusServiceUnavailable.
+// The endpoint is one of the gotoon.Endpoint names, or empty for any endpoint.
+func (s *Server) FailNext(endpoint string, status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, fault{endpoint: endpoint, status: status, n: n})
+}
+
+// ExpireTokens expires all issued access tokens; calls with them are rejected
+// with HTTP status 401.  If refreshTokens is set, the refresh tokens are expired
+// as well, so that the clients need to login again.
+func (s *Server) ExpireTokens(refreshTokens bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.access = make(map[string]time.Time)
+	if refreshTokens {
+		s.refresh = make(map[string]time.Time)
+	}
+}
+
+// Requests returns the number of requests made to the endpoint, one of the
+// gotoon.Endpoint names.
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[endpoint]
+}
+
+// now returns the current time of the Server.
+func (s *Server) now() time.Time {
+	if s.Clock != nil {
+		return s.Clock.Now()
+	}
+	return time.Now()
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+
+	endpoint, agreementID := route(r.URL.Path)
+
+	s.runSteps()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[endpoint]++
+
+	if status, ok := s.nextFault(endpoint); ok {
+		w.WriteHeader(status)
+		if status != http.StatusAccepted {
+			writeJSON(w, map[string]string{"fault": http.StatusText(status)})
+		}
+		return
+	}
+
+	switch endpoint {
+	case gotoon.EndpointAuthorize:
+		s.authorize(w, r)
+	case gotoon.EndpointToken:
+		s.token(w, r)
+	case gotoon.EndpointAgreements, gotoon.EndpointStatus, gotoon.EndpointGasFlows:
+		if !s.authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			writeJSON(w, map[string]string{"fault": "Invalid Access Token"})
+			return
+		}
+		s.data(w, r, endpoint, agreementID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// route returns the endpoint name and the agreement of the request path.
+func route(path string) (endpoint, agreementID string) {
+	switch path {
+	case "/authorize/legacy":
+		return gotoon.EndpointAuthorize, ""
+	case "/token":
+		return gotoon.EndpointToken, ""
+	case "/toon/v3/" + gotoon.EndpointAgreements:
+		return gotoon.EndpointAgreements, ""
+	}
+
+	rest := strings.TrimPrefix(path, "/toon/v3/")
+	if rest == path {
+		return "", ""
+	}
+	agreementID, endpoint, _ = strings.Cut(rest, "/")
+	return endpoint, agreementID
+}
+
+// nextFault returns the status of the next fault injected into the endpoint.
+func (s *Server) nextFault(endpoint string) (status int, ok bool) {
+	for i, f := range s.faults {
+		if f.endpoint != "" && f.endpoint != endpoint {
+			continue
+		}
+		s.faults[i].n--
+		if s.faults[i].n <= 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		return f.status, true
+	}
+	return 0, false
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil ||
+		r.PostForm.Get("client_id") != ConsumerKey ||
+		r.PostForm.Get("tenant_id") != TenantID ||
+		r.PostForm.Get("username") != Username ||
+		r.PostForm.Get("password") != Password {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]string{"fault": "Invalid credentials"})
+		return
+	}
+
+	code := newSecret()
+	s.codes[code] = true
+	w.Header().Set("Location", "http://127.0.0.1/?code="+code)
+	w.WriteHeader(http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil ||
+		r.PostForm.Get("client_id") != ConsumerKey ||
+		r.PostForm.Get("client_secret") != ConsumerSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]string{"fault": "Invalid client"})
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code := r.PostForm.Get("code")
+		if !s.codes[code] {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"fault": "Invalid authorization code"})
+			return
+		}
+		delete(s.codes, code)
+	case "refresh_token":
+		rt := r.PostForm.Get("refresh_token")
+		expiresAt, ok := s.refresh[rt]
+		if !ok || !s.now().Before(expiresAt) {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"fault": "Invalid refresh token"})
+			return
+		}
+		delete(s.refresh, rt)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"fault": "Unsupported grant type"})
+		return
+	}
+
+	access, refresh := newSecret(), newSecret()
+	s.access[access] = s.now().Add(s.TokenTTL)
+	s.refresh[refresh] = s.now().Add(s.RefreshTokenTTL)
+
+	// the Toon API returns the lifetimes as strings
+	writeJSON(w, map[string]string{
+		"access_token":             access,
+		"expires_in":               strconv.Itoa(int(s.TokenTTL.Seconds())),
+		"refresh_token":            refresh,
+		"refresh_token_expires_in": strconv.Itoa(int(s.RefreshTokenTTL.Seconds())),
+	})
+}
+
+// authorized reports whether the request has a valid access token.
+func (s *Server) authorized(r *http.Request) bool {
+	access := strings.TrimPrefix(r.Header.Get("authorization"), "Bearer ")
+	expiresAt, ok := s.access[access]
+	return ok && s.now().Before(expiresAt)
+}
+
+func (s *Server) data(w http.ResponseWriter, r *http.Request, endpoint, agreementID string) {
+
+	if endpoint == gotoon.EndpointAgreements {
+		writeJSON(w, s.fixture.Agreements)
+		return
+	}
+
+	if !s.hasAgreement(agreementID) {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]string{"fault": "Unknown agreement"})
+		return
+	}
+
+	switch endpoint {
+	case gotoon.EndpointStatus:
+		writeJSON(w, s.fixture.Status[agreementID])
+	case gotoon.EndpointGasFlows:
+		writeJSON(w, filterFlow(s.fixture.GasFlows[agreementID], r.URL.Query()))
+	}
+}
+
+func (s *Server) hasAgreement(agreementID string) bool {
+	for _, a := range s.fixture.Agreements {
+		if a.AgreementID == agreementID {
+			return true
+		}
+	}
+	return false
+}
+
+// filterFlow returns the data points of the flow between the fromTime and toTime
+// query parameters, in milliseconds since the epoch.
+func filterFlow(flow gotoon.FlowData, q map[string][]string) gotoon.FlowData {
+
+	bound := func(name string, def int64) int64 {
+		if v, ok := q[name]; ok && len(v) > 0 {
+			if ms, err := strconv.ParseInt(v[0], 10, 64); err == nil {
+				return ms
+			}
+		}
+		return def
+	}
+	from := bound("fromTime", 0)
+	to := bound("toTime", 1<<62)
+
+	filter := func(points []gotoon.FlowDataPoint) (out []gotoon.FlowDataPoint) {
+		for _, p := range points {
+			if ms := p.Timestamp.UnixMilli(); ms >= from && ms <= to {
+				out = append(out, p)
+			}
+		}
+		return
+	}
+
+	return gotoon.FlowData{
+		Hours:  filter(flow.Hours),
+		Days:   filter(flow.Days),
+		Weeks:  filter(flow.Weeks),
+		Months: filter(flow.Months),
+		Years:  filter(flow.Years),
+		Extra:  flow.Extra,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newSecret() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
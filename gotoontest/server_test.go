@@ -0,0 +1,171 @@
+package gotoontest_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hurngchunlee/gotoon"
+	"github.com/hurngchunlee/gotoon/gotoontest"
+)
+
+func TestServerFixture(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	toon := s.Toon()
+
+	agreements, err := toon.GetAgreements()
+	if err != nil {
+		t.Fatalf("fail getting agreements: %+v", err)
+	}
+	if len(agreements) != 1 || agreements[0].AgreementID != "10000001" {
+		t.Fatalf("unexpected agreements: %+v", agreements)
+	}
+
+	status, err := toon.GetStatus(agreements[0])
+	if err != nil {
+		t.Fatalf("fail getting status: %+v", err)
+	}
+	if status.ThermostatInfo.CurrentSetPoint.Celsius() != 20 {
+		t.Errorf("unexpected setpoint: %s", status.ThermostatInfo.CurrentSetPoint)
+	}
+
+	// only the last half hour out of the hour of 5-minute data points
+	now := time.Now()
+	flow, err := toon.GetGasFlow(agreements[0], now.Add(-32*time.Minute), now)
+	if err != nil {
+		t.Fatalf("fail getting gas flow: %+v", err)
+	}
+	if n := len(flow.Hours); n != 6 {
+		t.Errorf("unexpected number of data points: %d", n)
+	}
+
+	// login once for all calls
+	if n := s.Requests(gotoon.EndpointAuthorize); n != 1 {
+		t.Errorf("unexpected number of logins: %d", n)
+	}
+}
+
+func TestServerSeed(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	a := gotoon.Agreement{AgreementID: "20000002", HeatingType: gotoon.HeatingDistrict}
+	s.Seed(gotoontest.Fixture{Agreements: []gotoon.Agreement{a}})
+	s.UpdateStatus(a.AgreementID, func(status *gotoon.Status) {
+		status.ThermostatInfo.CurrentDisplayTemp = gotoon.TemperatureFromCelsius(17.5)
+	})
+
+	toon := s.Toon()
+
+	status, err := toon.GetStatus(a)
+	if err != nil {
+		t.Fatalf("fail getting status: %+v", err)
+	}
+	if status.ThermostatInfo.CurrentDisplayTemp != 1750 {
+		t.Errorf("unexpected temperature: %s", status.ThermostatInfo.CurrentDisplayTemp)
+	}
+
+	var apiErr *gotoon.APIError
+	_, err = toon.GetStatus(gotoon.Agreement{AgreementID: "10000001"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expect not found error: %+v", err)
+	}
+}
+
+func TestServerFailNext(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	toon := s.Toon()
+	a := gotoon.Agreement{AgreementID: "10000001"}
+
+	// the client waits for the result of accepted requests
+	s.FailNext(gotoon.EndpointStatus, http.StatusAccepted, 3)
+	if _, err := toon.GetStatus(a); err != nil {
+		t.Fatalf("fail getting status: %+v", err)
+	}
+	if n := s.Requests(gotoon.EndpointStatus); n != 4 {
+		t.Errorf("unexpected number of status requests: %d", n)
+	}
+
+	// server errors fail the call without a RetryPolicy
+	var apiErr *gotoon.APIError
+	s.FailNext("", http.StatusServiceUnavailable, 1)
+	if _, err := toon.GetStatus(a); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expect service unavailable error: %+v", err)
+	}
+
+	// and are retried with one
+	toon.RetryPolicy = &gotoon.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryStatusCodes: []int{http.StatusServiceUnavailable}}
+	s.FailNext(gotoon.EndpointStatus, http.StatusServiceUnavailable, 2)
+	if _, err := toon.GetStatus(a); err != nil {
+		t.Errorf("fail getting status with retries: %+v", err)
+	}
+}
+
+func TestServerTokens(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	clock := gotoontest.NewClock(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	s.Clock = clock
+
+	toon := s.Toon()
+	a := gotoon.Agreement{AgreementID: "10000001"}
+
+	if _, err := toon.GetStatus(a); err != nil {
+		t.Fatalf("fail getting status: %+v", err)
+	}
+
+	// the client considers the token expired 3 minutes ahead of its lifetime
+	clock.Advance(s.TokenTTL - 3*time.Minute + time.Second)
+	if _, err := toon.GetStatus(a); err != nil {
+		t.Fatalf("fail getting status after token expiry: %+v", err)
+	}
+	if n := s.Requests(gotoon.EndpointAuthorize); n != 1 {
+		t.Errorf("unexpected number of logins: %d", n)
+	}
+	if n := s.Requests(gotoon.EndpointToken); n != 2 {
+		t.Errorf("expect token refreshed, token requests: %d", n)
+	}
+
+	// tokens revoked by the server are renewed by a refresh
+	s.ExpireTokens(false)
+	if _, err := toon.GetStatus(a); err != nil {
+		t.Errorf("fail getting status with revoked access token: %+v", err)
+	}
+	if n := s.Requests(gotoon.EndpointToken); n != 3 {
+		t.Errorf("expect token refreshed, token requests: %d", n)
+	}
+
+	// or by a login if the refresh token is revoked as well
+	s.ExpireTokens(true)
+	if _, err := toon.GetStatus(a); err != nil {
+		t.Errorf("fail getting status with revoked tokens: %+v", err)
+	}
+	if n := s.Requests(gotoon.EndpointAuthorize); n != 2 {
+		t.Errorf("expect login again, logins: %d", n)
+	}
+
+	// a token rejected again fails the call
+	var apiErr *gotoon.APIError
+	s.FailNext(gotoon.EndpointStatus, http.StatusUnauthorized, 2)
+	if _, err := toon.GetStatus(a); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expect unauthorized error: %+v", err)
+	}
+}
+
+func TestServerCredentials(t *testing.T) {
+	s := gotoontest.NewServer()
+	defer s.Close()
+
+	toon := s.Toon()
+	toon.Password = "wrong"
+
+	if _, err := toon.GetAgreements(); err == nil {
+		t.Errorf("expect login failure with wrong password")
+	}
+}
@@ -0,0 +1,58 @@
+package gotoon
+
+import (
+	"net/http"
+)
+
+// Call describes a HTTP request made to the Toon API.  It is passed to the
+// Interceptors of the Toon.
+type Call struct {
+	// Endpoint is the name of the API endpoint, e.g. EndpointStatus or EndpointToken.
+	Endpoint string
+	// AgreementID is the agreement the call refers to; it is empty if the call
+	// doesn't refer to an agreement, e.g. for EndpointAgreements.
+	AgreementID string
+	// Write indicates whether the call is not idempotent.
+	Write bool
+	// Request is the HTTP request to be made.  Interceptors may modify it, e.g. to
+	// add a header, before passing the call on.
+	Request *http.Request
+}
+
+// Invoker makes the call and returns the HTTP response.
+type Invoker func(call *Call) (*http.Response, error)
+
+// Interceptor runs around a call to the Toon API.  It passes the call on to next
+// to make it, and may inspect or modify the call and the response; or it may
+// return a response or an error without calling next at all.
+//
+// An example of an Interceptor adding a custom header to every request:
+//
+//	func(call *gotoon.Call, next gotoon.Invoker) (*http.Response, error) {
+//	    call.Request.Header.Set("x-request-source", "dashboard")
+//	    return next(call)
+//	}
+type Interceptor func(call *Call, next Invoker) (*http.Response, error)
+
+// intercept makes the HTTP request req of the request r using the client c,
+// through the Interceptors of the Toon.
+func (t *Toon) intercept(c *http.Client, r request, req *http.Request) (*http.Response, error) {
+
+	invoke := func(call *Call) (*http.Response, error) {
+		return c.Do(call.Request)
+	}
+
+	for i := len(t.Interceptors) - 1; i >= 0; i-- {
+		interceptor, next := t.Interceptors[i], invoke
+		invoke = func(call *Call) (*http.Response, error) {
+			return interceptor(call, next)
+		}
+	}
+
+	return invoke(&Call{
+		Endpoint:    r.endpoint,
+		AgreementID: r.agreementID,
+		Write:       r.write,
+		Request:     req,
+	})
+}
@@ -0,0 +1,78 @@
+package gotoon
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("x-test")))
+	}))
+	defer s.Close()
+
+	var order []string
+	record := func(name string) Interceptor {
+		return func(call *Call, next Invoker) (*http.Response, error) {
+			order = append(order, name+":"+call.Endpoint+":"+call.AgreementID)
+			call.Request.Header.Set("x-test", name)
+			return next(call)
+		}
+	}
+
+	toon := Toon{Interceptors: []Interceptor{record("outer"), record("inner")}}
+
+	r := request{endpoint: EndpointStatus, agreementID: "1234", method: "GET", url: s.URL, auth: true}
+	res, err := toon.send(context.Background(), s.Client(), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+
+	if expected := []string{"outer:status:1234", "inner:status:1234"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected interceptors %v, got %v", expected, order)
+	}
+
+	// an interceptor injecting a fault without making the call
+	fault := errors.New("injected fault")
+	toon.Interceptors = []Interceptor{func(call *Call, next Invoker) (*http.Response, error) {
+		return nil, fault
+	}}
+	if _, err = toon.send(context.Background(), s.Client(), r); !errors.Is(err, fault) {
+		t.Errorf("expected injected fault, got %+v", err)
+	}
+}
+
+func TestInterceptorShortCircuitToken(t *testing.T) {
+
+	// an interceptor answering the calls without making them, i.e. without a request
+	// in the responses
+	respond := func(call *Call, next Invoker) (*http.Response, error) {
+		res := &http.Response{StatusCode: 400, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("invalid_grant"))}
+		if call.Endpoint == EndpointAuthorize {
+			res.StatusCode = 302
+			res.Header.Set("Location", "http://127.0.0.1/?code=1234")
+		}
+		return res, nil
+	}
+
+	toon := Toon{ConsumerKey: "key", Interceptors: []Interceptor{respond}}
+	toon.Endpoints.TokenURL = "http://127.0.0.1/token"
+
+	var apiErr *APIError
+	if err := toon.getAccessToken(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != 400 || apiErr.URL != toon.Endpoints.TokenURL {
+		t.Errorf("expected token error, got %+v", err)
+	}
+
+	toon.accessToken.RefreshToken = "refresh"
+	if err := toon.refreshAccessToken(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != 400 || apiErr.URL != toon.Endpoints.TokenURL {
+		t.Errorf("expected token error, got %+v", err)
+	}
+}
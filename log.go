@@ -0,0 +1,74 @@
+package gotoon
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+)
+
+// Redacted replaces the value of a secret in the log and the printed Toon.
+const Redacted = "REDACTED"
+
+// secretParams are the names of request parameters and JSON fields holding a secret.
+var secretParams = map[string]bool{
+	"password":      true,
+	"client_secret": true,
+	"code":          true,
+	"access_token":  true,
+	"refresh_token": true,
+}
+
+// IsSecretParam reports whether name is the name of a request parameter or a JSON
+// field holding a secret, e.g. "password" or "refresh_token".
+func IsSecretParam(name string) bool { return secretParams[name] }
+
+// RedactValues returns a copy of v in which the values of secret parameters, e.g.
+// the password and the refresh token, and of the given other parameters are
+// replaced by Redacted.
+func RedactValues(v url.Values, others ...string) url.Values {
+	r := make(url.Values, len(v))
+	for k, vs := range v {
+		if secretParams[k] || contains(others, k) {
+			r[k] = []string{Redacted}
+			continue
+		}
+		r[k] = append([]string(nil), vs...)
+	}
+	return r
+}
+
+// contains reports whether s contains the string e.
+func contains(s []string, e string) bool {
+	for _, v := range s {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+// log writes a log record with the given level, message and attributes to the
+// Logger of the Toon, if it is set.
+//
+// Attributes must never contain secrets; use RedactValues for request parameters.
+func (t *Toon) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if t.Logger == nil {
+		return
+	}
+	t.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// requestAttrs returns the log attributes describing the request r.
+func requestAttrs(r request) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("method", r.method),
+		slog.String("url", r.url),
+	}
+	if len(r.query) > 0 {
+		attrs = append(attrs, slog.String("query", RedactValues(r.query).Encode()))
+	}
+	if len(r.form) > 0 {
+		attrs = append(attrs, slog.String("form", RedactValues(r.form).Encode()))
+	}
+	return attrs
+}
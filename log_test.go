@@ -0,0 +1,59 @@
+package gotoon
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLogRedactsSecrets(t *testing.T) {
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer s.Close()
+
+	var buf bytes.Buffer
+	toon := Toon{
+		Logger:      slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		accessToken: token{AccessToken: "s3cr3t-access-token"},
+	}
+
+	v := url.Values{}
+	v.Set("username", "user")
+	v.Set("password", "s3cr3t-password")
+	v.Set("client_secret", "s3cr3t-consumer-secret")
+	v.Set("code", "s3cr3t-code")
+	v.Set("refresh_token", "s3cr3t-refresh-token")
+
+	res, err := toon.send(context.Background(), s.Client(), request{method: "POST", url: s.URL, form: v, auth: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+
+	if !strings.Contains(buf.String(), "username=user") {
+		t.Errorf("request not logged: %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Errorf("secret logged: %s", buf.String())
+	}
+}
+
+func TestRedactValues(t *testing.T) {
+
+	v := url.Values{"username": {"user"}, "password": {"s3cr3t"}, "grant_type": {"password"}}
+
+	r := RedactValues(v, "username")
+	if r.Get("username") != Redacted || r.Get("password") != Redacted || r.Get("grant_type") != "password" {
+		t.Errorf("unexpected redacted values: %v", r)
+	}
+	if v.Get("password") != "s3cr3t" {
+		t.Errorf("values changed in place: %v", v)
+	}
+}
@@ -0,0 +1,137 @@
+package gotoon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Manager holds the Toon clients of many accounts, keyed by an account name of
+// choice.  The clients share one HTTP client, and thus its connection pool;
+// the rate limit is shared by the clients with the same consumer key.
+//
+// Accounts can be added and removed while the Manager is in use.  The zero Manager
+// is empty and ready to use, with a default HTTP client shared by the clients.
+type Manager struct {
+	// RateLimit is applied to the added clients without a RateLimit of their own.
+	// As the rate limit is shared by all clients with the same consumer key, it has
+	// no effect on a consumer key whose rate limit has already been used by another
+	// client, inside or outside the Manager; see RateLimit.
+	RateLimit *RateLimit
+
+	mu       sync.RWMutex
+	client   *http.Client
+	accounts map[string]*Toon
+}
+
+// AccountAgreement is an Agreement of an account in the Manager.
+type AccountAgreement struct {
+	// Account is the name of the account in the Manager.
+	Account   string
+	Agreement Agreement
+}
+
+// NewManager creates an empty Manager.  The clients of the accounts share the
+// given HTTP client, or a new default client if it is nil.
+func NewManager(client *http.Client) *Manager {
+	if client == nil {
+		client = newHTTPSClient(0)
+	}
+	return &Manager{
+		client:   client,
+		accounts: make(map[string]*Toon),
+	}
+}
+
+// Add adds the Toon client of the account.  The client is given the shared HTTP
+// client and RateLimit of the Manager, unless it has its own.  The HTTPClient and
+// RateLimit fields of t are set in place, so t must not be in use while it is
+// added; the caller sees the shared values afterwards.
+func (m *Manager) Add(account string, t *Toon) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.accounts[account]; ok {
+		return fmt.Errorf("account already exists: %s", account)
+	}
+
+	// the zero Manager
+	if m.accounts == nil {
+		m.accounts = make(map[string]*Toon)
+	}
+	if m.client == nil {
+		m.client = newHTTPSClient(0)
+	}
+
+	if t.HTTPClient == nil {
+		t.HTTPClient = m.client
+	}
+	if t.RateLimit == nil {
+		t.RateLimit = m.RateLimit
+	}
+	m.accounts[account] = t
+	return nil
+}
+
+// Remove removes the account, and reports whether the account existed.
+func (m *Manager) Remove(account string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.accounts[account]
+	delete(m.accounts, account)
+	return ok
+}
+
+// Get returns the Toon client of the account.
+func (m *Manager) Get(account string) (t *Toon, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok = m.accounts[account]
+	return
+}
+
+// Accounts returns the names of the accounts in alphabetical order.
+func (m *Manager) Accounts() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.accounts))
+	for name := range m.accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetAgreements gets the agreements of all accounts.  The agreements of the
+// accounts that succeeded are returned, together with an error joining the
+// errors of the accounts that failed.
+func (m *Manager) GetAgreements(ctx context.Context) (agreements []AccountAgreement, err error) {
+
+	var errs []error
+
+	for _, account := range m.Accounts() {
+		t, ok := m.Get(account)
+		if !ok {
+			// removed in the meantime
+			continue
+		}
+
+		as, aerr := t.getAgreements(ctx)
+		if aerr != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", account, aerr))
+			continue
+		}
+		for _, a := range as {
+			agreements = append(agreements, AccountAgreement{Account: account, Agreement: a})
+		}
+	}
+
+	err = errors.Join(errs...)
+	return
+}
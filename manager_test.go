@@ -0,0 +1,119 @@
+package gotoon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") == "Bearer broken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// every account has one agreement named after its access token
+		w.Write([]byte(`[{"agreementId": "` + strings.TrimPrefix(r.Header.Get("authorization"), "Bearer ") + `"}]`))
+	}))
+	defer s.Close()
+
+	newToon := func(accessToken string) *Toon {
+		return &Toon{
+			Endpoints: Endpoints{APIBaseURL: s.URL},
+			accessToken: token{
+				AccessToken:           accessToken,
+				ExpiresAt:             time.Now().Add(time.Hour),
+				RefreshTokenExpiresAt: time.Now().Add(time.Hour),
+			},
+		}
+	}
+
+	m := NewManager(s.Client())
+	m.Add("b", newToon("agreement-b"))
+	m.Add("a", newToon("agreement-a"))
+	m.Add("c", newToon("broken"))
+
+	if err := m.Add("a", newToon("agreement-a")); err == nil {
+		t.Errorf("duplicate account added")
+	}
+	if toon, _ := m.Get("a"); toon.HTTPClient != s.Client() {
+		t.Errorf("HTTP client not shared")
+	}
+
+	agreements, err := m.GetAgreements(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "account c") {
+		t.Errorf("error of account c not reported: %+v", err)
+	}
+
+	var ids []string
+	for _, a := range agreements {
+		ids = append(ids, a.Account+":"+a.Agreement.AgreementID)
+	}
+	if expected := []string{"a:agreement-a", "b:agreement-b"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected agreements %v, got %v", expected, ids)
+	}
+
+	if !m.Remove("c") || m.Remove("c") {
+		t.Errorf("unexpected result of removing account")
+	}
+	if _, err = m.GetAgreements(context.Background()); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func TestManagerAddSharedSettings(t *testing.T) {
+
+	m := NewManager(nil)
+	m.RateLimit = &RateLimit{Rate: 1, Burst: 1}
+
+	// the shared settings are set on the added Toon itself
+	toon := &Toon{ConsumerKey: "manager-test-key"}
+	if err := m.Add("a", toon); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if toon.HTTPClient != m.client || toon.RateLimit != m.RateLimit {
+		t.Errorf("shared settings not set on the added Toon: %+v", toon)
+	}
+
+	// settings of its own are kept
+	own := &RateLimit{Rate: 5, Burst: 5}
+	client := &http.Client{}
+	toon = &Toon{ConsumerKey: "manager-test-key", HTTPClient: client, RateLimit: own}
+	m.Add("b", toon)
+	if toon.HTTPClient != client || toon.RateLimit != own {
+		t.Errorf("own settings of the added Toon replaced: %+v", toon)
+	}
+
+	// the limiter of a consumer key is created by the first RateLimit used with it,
+	// which makes the RateLimit of the Manager ineffective for the key
+	first := &Toon{ConsumerKey: "manager-test-used-key", RateLimit: &RateLimit{Rate: 10, Burst: 10}}
+	b := first.limiter()
+
+	toon = &Toon{ConsumerKey: "manager-test-used-key"}
+	m.Add("c", toon)
+	if toon.limiter() != b || b.rate != 10 {
+		t.Errorf("expected the existing limiter of the consumer key")
+	}
+}
+
+func TestManagerZeroValue(t *testing.T) {
+
+	m := &Manager{RateLimit: &RateLimit{Rate: 1, Burst: 1}}
+	if _, ok := m.Get("a"); ok || len(m.Accounts()) != 0 || m.Remove("a") {
+		t.Errorf("zero Manager not empty")
+	}
+
+	a, b := &Toon{}, &Toon{}
+	if err := m.Add("a", a); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	m.Add("b", b)
+	if a.HTTPClient == nil || a.HTTPClient != b.HTTPClient || a.RateLimit != m.RateLimit {
+		t.Errorf("shared settings not set by the zero Manager")
+	}
+}
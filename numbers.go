@@ -0,0 +1,114 @@
+package gotoon
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Int is an integer of the models decoded from an integer, a float, a string of
+// a number or null in JSON.
+type Int int
+
+// UnmarshalJSON converts input number or string into an integer; a float is
+// rounded to the nearest integer.
+func (i *Int) UnmarshalJSON(s []byte) error {
+	return unmarshalInt(s, (*int)(i))
+}
+
+// Float is a float of the models decoded from a number, a string of a number or
+// null in JSON.
+type Float float64
+
+// UnmarshalJSON converts input number or string into a float.
+func (f *Float) UnmarshalJSON(s []byte) error {
+	return unmarshalFloat(s, (*float64)(f))
+}
+
+// UnmarshalJSON converts input number or string into a Temperature.
+func (t *Temperature) UnmarshalJSON(s []byte) error { return unmarshalInt(s, (*int)(t)) }
+
+// UnmarshalJSON converts input number or string into an Energy.
+func (e *Energy) UnmarshalJSON(s []byte) error { return unmarshalFloat(s, (*float64)(e)) }
+
+// UnmarshalJSON converts input number or string into a Volume.
+func (v *Volume) UnmarshalJSON(s []byte) error { return unmarshalFloat(s, (*float64)(v)) }
+
+// UnmarshalJSON converts input number or string into a GasFlow.
+func (f *GasFlow) UnmarshalJSON(s []byte) error { return unmarshalFloat(s, (*float64)(f)) }
+
+// UnmarshalJSON converts input number or string into a Power.
+func (p *Power) UnmarshalJSON(s []byte) error { return unmarshalFloat(s, (*float64)(p)) }
+
+// UnmarshalJSON converts input number or string into Money.
+func (m *Money) UnmarshalJSON(s []byte) error { return unmarshalFloat(s, (*float64)(m)) }
+
+// UnmarshalJSON converts input number or string into an ActiveState.
+func (a *ActiveState) UnmarshalJSON(s []byte) error { return unmarshalInt(s, (*int)(a)) }
+
+// UnmarshalJSON converts input number or string into a ProgramState.
+func (p *ProgramState) UnmarshalJSON(s []byte) error { return unmarshalInt(s, (*int)(p)) }
+
+// unmarshalInt sets v to the integer of the JSON value s; v is unchanged if s is null.
+func unmarshalInt(s []byte, v *int) error {
+	i, ok, err := parseInt(s)
+	if ok {
+		*v = int(i)
+	}
+	return err
+}
+
+// unmarshalFloat sets v to the float of the JSON value s; v is unchanged if s is null.
+func unmarshalFloat(s []byte, v *float64) error {
+	f, ok, err := parseFloat(s)
+	if ok {
+		*v = f
+	}
+	return err
+}
+
+// numberText returns the text of the number in the JSON value s, which is either
+// a number or a string containing a number.  It returns false if s is null or an
+// empty string.
+func numberText(s []byte) (string, bool) {
+	s = bytes.TrimSpace(s)
+	if string(s) == "null" {
+		return "", false
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = bytes.TrimSpace(s[1 : len(s)-1])
+	}
+	return string(s), len(s) > 0
+}
+
+// parseInt parses the JSON value s into an integer, rounding a float to the
+// nearest integer.  It returns false if s is null or an empty string.
+func parseInt(s []byte) (i int64, ok bool, err error) {
+	n, ok := numberText(s)
+	if !ok {
+		return
+	}
+	if i, err = strconv.ParseInt(n, 10, 64); err == nil {
+		return
+	}
+	f, err := strconv.ParseFloat(n, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) >= math.MaxInt64 {
+		return 0, false, fmt.Errorf("Cannot unmarshal value to integer: %s", s)
+	}
+	return int64(math.Round(f)), true, nil
+}
+
+// parseFloat parses the JSON value s into a float.  It returns false if s is null
+// or an empty string.
+func parseFloat(s []byte) (f float64, ok bool, err error) {
+	n, ok := numberText(s)
+	if !ok {
+		return
+	}
+	f, err = strconv.ParseFloat(n, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false, fmt.Errorf("Cannot unmarshal value to float: %s", s)
+	}
+	return f, true, nil
+}
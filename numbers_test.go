@@ -0,0 +1,61 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestTolerantNumbers(t *testing.T) {
+
+	in := `{
+		"thermostatInfo": {"currentSetpoint": "2050", "currentDisplayTemp": 1987.6, "activeState": "2",
+			"errorFound": null, "currentModulationLevel": "", "lastUpdatedFromDisplay": "1540000000123"},
+		"powerUsage": {"value": "432", "dayCost": "1.23", "avgValue": "250.5", "isSmart": "1"},
+		"gasUsage": {"value": 12.0, "dayCost": 2, "meterReading": null}
+	}`
+
+	var status Status
+	if err := json.Unmarshal([]byte(in), &status); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	info := status.ThermostatInfo
+	if info.CurrentSetPoint != 2050 || info.CurrentDisplayTemp != 1988 || info.ActiveState != StateSleep ||
+		info.ErrorFound != 0 || info.LastUpdatedFromDisplay.UnixMilli() != 1540000000123 {
+		t.Errorf("unexpected thermostat info: %+v", info)
+	}
+
+	power := status.PowerUsage
+	if power.Value != 432 || power.DayCost != 1.23 || power.AvgValue != 250.5 || !power.IsSmart {
+		t.Errorf("unexpected power usage: %+v", power)
+	}
+
+	if status.GasUsage.Value != 12 || status.GasUsage.DayCost != 2 {
+		t.Errorf("unexpected gas usage: %+v", status.GasUsage)
+	}
+
+	var tk token
+	if err := json.Unmarshal([]byte(`{"expires_in": 1800, "refresh_token_expires_in": "3600"}`), &tk); err != nil ||
+		tk.ExpiresIn != 1800 || tk.RefreshTokenExpiresIn != 3600 {
+		t.Errorf("unexpected token: %+v, %+v", tk, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"currentSetpoint": "warm"}`), &info); err == nil {
+		t.Errorf("non-numeric string accepted")
+	}
+}
+
+func TestTolerantNumbersRange(t *testing.T) {
+	for _, in := range []string{`9223372036854775807.5`, `"9.3e18"`, `-9.3e18`, `1e400`} {
+		var i Int
+		if err := json.Unmarshal([]byte(in), &i); err == nil {
+			t.Errorf("%s: out of range number decoded into %d", in, i)
+		}
+	}
+
+	var i Int
+	if err := json.Unmarshal([]byte(`9223372036854775807`), &i); err != nil || i != math.MaxInt64 {
+		t.Errorf("unexpected maximum integer: %d, %v", i, err)
+	}
+}
@@ -0,0 +1,196 @@
+package gotoon
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// TenantIDs are the known tenants of the Toon API.
+var TenantIDs = []string{"eneco", "electrabel", "viesgo"}
+
+// Option configures the Toon created by NewToon.
+type Option func(t *Toon) error
+
+// NewToon creates a Toon configured by the given options, and validates that
+// the credentials required for authentication are set and the tenant is known.
+//
+// A Toon constructed as a struct literal remains usable; it is not validated.
+func NewToon(opts ...Option) (*Toon, error) {
+	t := &Toon{}
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// validate checks the configuration of the Toon.
+func (t *Toon) validate() error {
+
+	var errs []error
+
+	missing := func(field, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("missing %s", field))
+		}
+	}
+	missing("TenantID", t.TenantID)
+	missing("ConsumerKey", t.ConsumerKey)
+	// the secrets may be provided by the CredentialProvider at login
+	if t.Credentials == nil {
+		missing("ConsumerSecret", t.ConsumerSecret)
+		missing("Username", t.Username)
+		missing("Password", t.Password)
+	}
+
+	if t.TenantID != "" && !isKnownTenant(t.TenantID) {
+		errs = append(errs, fmt.Errorf("unknown TenantID: %s", t.TenantID))
+	}
+
+	return errors.Join(errs...)
+}
+
+func isKnownTenant(id string) bool {
+	for _, known := range TenantIDs {
+		if id == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WithTenant sets the tenant ID, e.g. "eneco".
+func WithTenant(tenantID string) Option {
+	return func(t *Toon) error {
+		t.TenantID = tenantID
+		return nil
+	}
+}
+
+// WithCredentials sets the username and password of the tenant account.
+func WithCredentials(username, password string) Option {
+	return func(t *Toon) error {
+		t.Username = username
+		t.Password = password
+		return nil
+	}
+}
+
+// WithCredentialProvider sets the provider of the secrets needed to login.
+func WithCredentialProvider(p CredentialProvider) Option {
+	return func(t *Toon) error {
+		t.Credentials = p
+		return nil
+	}
+}
+
+// WithConsumer sets the consumer key and secret of the Toon API.
+func WithConsumer(key, secret string) Option {
+	return func(t *Toon) error {
+		t.ConsumerKey = key
+		t.ConsumerSecret = secret
+		return nil
+	}
+}
+
+// WithEndpoints sets the URLs of the Toon API.
+func WithEndpoints(e Endpoints) Option {
+	return func(t *Toon) error {
+		t.Endpoints = e
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to make requests.
+func WithHTTPClient(c *http.Client) Option {
+	return func(t *Toon) error {
+		if c == nil {
+			return errors.New("nil HTTP client")
+		}
+		t.HTTPClient = c
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit of a HTTP request made with the default HTTP client.
+func WithTimeout(d time.Duration) Option {
+	return func(t *Toon) error {
+		if d <= 0 {
+			return fmt.Errorf("invalid timeout: %s", d)
+		}
+		t.Timeout = d
+		return nil
+	}
+}
+
+// WithLocation sets the time zone in which the times of the Status and FlowData
+// are presented.
+func WithLocation(loc *time.Location) Option {
+	return func(t *Toon) error {
+		t.Location = loc
+		return nil
+	}
+}
+
+// WithTokenStore sets the store persisting the access token between sessions.
+func WithTokenStore(s TokenStore) Option {
+	return func(t *Toon) error {
+		t.TokenStore = s
+		return nil
+	}
+}
+
+// WithLogger sets the logger of the requests and authentication events.
+func WithLogger(l *slog.Logger) Option {
+	return func(t *Toon) error {
+		t.Logger = l
+		return nil
+	}
+}
+
+// WithRetryPolicy sets the policy of retrying calls failed with transient errors.
+func WithRetryPolicy(p *RetryPolicy) Option {
+	return func(t *Toon) error {
+		t.RetryPolicy = p
+		return nil
+	}
+}
+
+// WithRateLimit sets the client-side rate limit of the calls.
+func WithRateLimit(l *RateLimit) Option {
+	return func(t *Toon) error {
+		t.RateLimit = l
+		return nil
+	}
+}
+
+// WithCache sets the cache of the responses.
+func WithCache(c *Cache) Option {
+	return func(t *Toon) error {
+		t.Cache = c
+		return nil
+	}
+}
+
+// WithInterceptors appends interceptors run around every HTTP request.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(t *Toon) error {
+		t.Interceptors = append(t.Interceptors, interceptors...)
+		return nil
+	}
+}
+
+// WithClock sets the clock used for the expiry of the tokens.
+func WithClock(now func() time.Time) Option {
+	return func(t *Toon) error {
+		t.Clock = now
+		return nil
+	}
+}
@@ -0,0 +1,33 @@
+package gotoon
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewToon(t *testing.T) {
+
+	toon, err := NewToon(
+		WithTenant("eneco"),
+		WithCredentials("user", "password"),
+		WithConsumer("key", "secret"),
+		WithTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if toon.TenantID != "eneco" || toon.ConsumerKey != "key" || toon.Timeout != time.Second {
+		t.Errorf("options not applied: %+v", toon)
+	}
+
+	_, err = NewToon(WithTenant("nuon"), WithCredentials("user", "password"))
+	if err == nil {
+		t.Fatalf("invalid configuration not detected")
+	}
+	for _, msg := range []string{"missing ConsumerKey", "missing ConsumerSecret", "unknown TenantID: nuon"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("error %q doesn't report %q", err, msg)
+		}
+	}
+}
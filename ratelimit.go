@@ -0,0 +1,194 @@
+package gotoon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRateLimitedRetries is the maximum number of times a call rejected by the
+// Toon API with HTTP status 429 is retried when the RateLimit is blocking.
+const maxRateLimitedRetries = 5
+
+// ErrRateLimited is the error reported when a call is not made, or rejected by
+// the Toon API, because the request quota of the consumer key is exhausted.
+// Use errors.Is to check for it.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitError is returned when a call fails because of rate limiting, either
+// by the client-side RateLimit or by the Toon API (HTTP status 429).
+type RateLimitError struct {
+	// RetryAfter is the time to wait before a new call can be made.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrRateLimited, e.RetryAfter)
+}
+
+// Is makes the RateLimitError match ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimit configures the client-side rate limiting of calls to the Toon API,
+// using a token bucket.  The bucket is shared by all calls made with the same
+// ConsumerKey; the first RateLimit used with a ConsumerKey determines the rate
+// and the burst of the bucket.
+type RateLimit struct {
+	// Rate is the sustained number of requests per second.  If not positive, the
+	// requests are not throttled and only HTTP status 429 responses are handled.
+	Rate float64 `json:"rate"`
+	// Burst is the maximum number of requests that can be made at once.
+	Burst int `json:"burst"`
+	// FailFast makes a call fail with a RateLimitError if there is no capacity left,
+	// instead of blocking until capacity frees up.
+	FailFast bool `json:"failFast"`
+}
+
+// tokenBucket implements the token bucket algorithm.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// pausedUntil is the time before which no request is allowed, e.g. as requested
+	// by the Retry-After header of a HTTP status 429 response.
+	pausedUntil time.Time
+}
+
+// limiters keeps the token buckets by consumer key.
+var limiters = struct {
+	sync.Mutex
+	m map[string]*tokenBucket
+}{m: make(map[string]*tokenBucket)}
+
+// limiterFor returns the token bucket of the given consumer key, creating it from
+// the RateLimit l if it doesn't exist.
+func limiterFor(consumerKey string, l *RateLimit) *tokenBucket {
+	limiters.Lock()
+	defer limiters.Unlock()
+
+	b, ok := limiters.m[consumerKey]
+	if !ok {
+		b = newTokenBucket(l.Rate, l.Burst)
+		limiters.m[consumerKey] = b
+	}
+	return b
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket, and returns the time to wait before the
+// token can be used.  If failFast is set and the token is not immediately available,
+// the token is not taken and ok is false.
+func (b *tokenBucket) reserve(now time.Time, failFast bool) (wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens < 1 {
+			wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+	}
+
+	if d := b.pausedUntil.Sub(now); d > wait {
+		wait = d
+	}
+
+	if wait > 0 && failFast {
+		return wait, false
+	}
+
+	if b.rate > 0 {
+		b.tokens--
+	}
+	return wait, true
+}
+
+// cancel returns a reserved token to the bucket.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+1)
+	}
+}
+
+// pause disallows requests until the given time.
+func (b *tokenBucket) pause(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// take takes a token from the bucket, waiting until it is available unless
+// failFast is set.
+func (b *tokenBucket) take(ctx context.Context, failFast bool) error {
+
+	wait, ok := b.reserve(time.Now(), failFast)
+	if !ok {
+		return &RateLimitError{RetryAfter: wait}
+	}
+
+	if wait <= 0 {
+		return nil
+	}
+
+	if err := sleep(ctx, wait); err != nil {
+		b.cancel()
+		return err
+	}
+	return nil
+}
+
+// limiter returns the token bucket shared by calls with the ConsumerKey of the
+// Toon, or nil if the Toon has no RateLimit.
+func (t *Toon) limiter() *tokenBucket {
+	if t.RateLimit == nil {
+		return nil
+	}
+	return limiterFor(t.ConsumerKey, t.RateLimit)
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// response res, defaulting to one second.
+func retryAfter(res *http.Response) time.Duration {
+
+	v := res.Header.Get("Retry-After")
+
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second
+	}
+
+	if d, err := http.ParseTime(v); err == nil {
+		if wait := time.Until(d); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+
+	return time.Second
+}
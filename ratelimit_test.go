@@ -0,0 +1,68 @@
+package gotoon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+
+	b := newTokenBucket(10, 2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if wait, ok := b.reserve(now, true); !ok || wait != 0 {
+			t.Fatalf("request %d within burst is throttled: %s", i, wait)
+		}
+	}
+
+	if _, ok := b.reserve(now, true); ok {
+		t.Errorf("request beyond burst is not throttled in fail-fast mode")
+	}
+
+	if wait, ok := b.reserve(now, false); !ok || wait != 100*time.Millisecond {
+		t.Errorf("expected wait of 100ms, got %s", wait)
+	}
+
+	b.pause(now.Add(time.Second))
+	if wait, _ := b.reserve(now.Add(time.Second/2), false); wait != time.Second/2 {
+		t.Errorf("expected wait of 500ms while paused, got %s", wait)
+	}
+}
+
+func TestRateLimitTooManyRequests(t *testing.T) {
+
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	r := request{method: "GET", url: s.URL, auth: true}
+
+	toon := Toon{ConsumerKey: "test-blocking", RateLimit: &RateLimit{Rate: 100, Burst: 1}}
+	res, err := toon.send(context.Background(), s.Client(), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+
+	calls = 0
+	toon = Toon{ConsumerKey: "test-failfast", RateLimit: &RateLimit{Rate: 100, Burst: 1, FailFast: true}}
+	if _, err = toon.send(context.Background(), s.Client(), r); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %+v", err)
+	}
+}
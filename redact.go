@@ -0,0 +1,126 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// mask returns the redacted replacement of the secret s, or an empty string if
+// s is empty so that a missing secret remains recognisable.
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// tokenView is the representation of a token with the secrets masked.
+type tokenView struct {
+	AccessToken           string    `json:"access_token"`
+	ExpiresIn             int       `json:"expires_in"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresIn int       `json:"refresh_token_expires_in"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+func (t token) view() tokenView {
+	return tokenView{
+		AccessToken:           mask(t.AccessToken),
+		ExpiresIn:             int(t.ExpiresIn),
+		ExpiresAt:             t.ExpiresAt,
+		RefreshToken:          mask(t.RefreshToken),
+		RefreshTokenExpiresIn: int(t.RefreshTokenExpiresIn),
+		RefreshTokenExpiresAt: t.RefreshTokenExpiresAt,
+	}
+}
+
+// Format formats the token with the access and refresh tokens masked.
+func (t token) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), t.view())
+}
+
+func (t token) String() string { return fmt.Sprintf("%+v", t.view()) }
+
+// LogValue logs the expiry times of the token, but not the tokens themselves.
+func (t token) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("accessToken", mask(t.AccessToken)),
+		slog.Time("expiresAt", t.ExpiresAt),
+		slog.String("refreshToken", mask(t.RefreshToken)),
+		slog.Time("refreshTokenExpiresAt", t.RefreshTokenExpiresAt),
+	)
+}
+
+// MarshalJSON marshals the token with the access and refresh tokens masked.
+func (t token) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.view())
+}
+
+// toonView is the representation of a Toon with the secrets masked.
+type toonView struct {
+	Username       string
+	Password       string
+	TenantID       string
+	ConsumerKey    string
+	ConsumerSecret string
+	Endpoints      Endpoints
+	Timeout        time.Duration
+	AccessToken    tokenView
+}
+
+// view returns the toonView of the Toon, with the access token read under the
+// token lock.
+func (t *Toon) view() toonView {
+	return toonView{
+		Username:       t.Username,
+		Password:       mask(t.Password),
+		TenantID:       t.TenantID,
+		ConsumerKey:    t.ConsumerKey,
+		ConsumerSecret: mask(t.ConsumerSecret),
+		Endpoints:      t.Endpoints,
+		Timeout:        t.Timeout,
+		AccessToken:    t.token().view(),
+	}
+}
+
+// token returns a copy of the access token, read under the token lock.
+func (t *Toon) token() token {
+	mu := t.tokenLock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	return t.accessToken
+}
+
+// Format formats the Toon with the password, the consumer secret and the tokens
+// masked, so that printing a Toon with e.g. fmt.Printf("%+v", toon) doesn't leak
+// them.  The expiry times of the tokens are shown.
+//
+// The methods have pointer receivers, as the access token is read under the token
+// lock; a Toon is printed and logged by pointer, as returned by NewToon.
+func (t *Toon) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, verb), t.view())
+}
+
+func (t *Toon) String() string { return fmt.Sprintf("%+v", t.view()) }
+
+// LogValue logs the Toon with the password, the consumer secret and the tokens masked.
+func (t *Toon) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("username", t.Username),
+		slog.String("password", mask(t.Password)),
+		slog.String("tenantID", t.TenantID),
+		slog.String("consumerKey", t.ConsumerKey),
+		slog.String("consumerSecret", mask(t.ConsumerSecret)),
+		slog.Any("accessToken", t.token()),
+	)
+}
+
+// MarshalJSON marshals the Toon with the password, the consumer secret and the
+// tokens masked.
+func (t *Toon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.view())
+}
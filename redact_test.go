@@ -0,0 +1,76 @@
+package gotoon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactToon(t *testing.T) {
+
+	expiresAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	toon := &Toon{
+		Username:       "user",
+		Password:       "s3cr3t-password",
+		TenantID:       "eneco",
+		ConsumerKey:    "key",
+		ConsumerSecret: "s3cr3t-consumer",
+		accessToken: token{
+			AccessToken:  "s3cr3t-access",
+			RefreshToken: "s3cr3t-refresh",
+			ExpiresAt:    expiresAt,
+		},
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("toon", "toon", toon)
+	j, err := json.Marshal(toon)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	outputs := map[string]string{
+		"%v":    fmt.Sprintf("%v", toon),
+		"%+v":   fmt.Sprintf("%+v", toon),
+		"%#v":   fmt.Sprintf("%#v", toon),
+		"token": fmt.Sprintf("%+v", toon.accessToken),
+		"str":   toon.String(),
+		"slog":  buf.String(),
+		"json":  string(j),
+	}
+
+	for name, out := range outputs {
+		if strings.Contains(out, "s3cr3t") {
+			t.Errorf("%s leaks secret: %s", name, out)
+		}
+		if name != "slog" && name != "json" && name != "%#v" && !strings.Contains(out, "2026-01-02 03:04:05") {
+			t.Errorf("%s doesn't show expiry time: %s", name, out)
+		}
+	}
+	if !strings.Contains(outputs["slog"], "2026-01-02T03:04:05") || !strings.Contains(outputs["json"], "2026-01-02T03:04:05") {
+		t.Errorf("expiry time not logged nor marshalled: %s, %s", outputs["slog"], outputs["json"])
+	}
+}
+
+func TestRedactToonConcurrently(t *testing.T) {
+
+	toon := &Toon{accessToken: token{AccessToken: "s3cr3t-access"}}
+
+	// printing the Toon while its token is renewed, run with -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			toon.expireToken("s3cr3t-access")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = fmt.Sprintf("%+v", toon)
+	}
+	<-done
+}
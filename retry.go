@@ -0,0 +1,156 @@
+package gotoon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how calls to the Toon API failed with a transient error,
+// e.g. a network error, a connection reset or a 5xx response, are retried.
+//
+// Idempotent GET requests are retried automatically; requests changing the state
+// of the Toon device are only retried when RetryWrites is set.  The requests of the
+// login and the token refresh are retried when they fail without a response, e.g.
+// with a connection reset, and otherwise only when RetryWrites is set.
+//
+// A GET request answered with HTTP status 202, i.e. the Toon API is still
+// processing it, is repeated following the RetryPolicy as well; without a
+// RetryPolicy it is repeated following acceptedPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the upper limit of the delay between two attempts.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the delay grows after every retry.
+	Multiplier float64
+	// Jitter is the fraction (between 0 and 1) of the delay that is randomised,
+	// so that clients failed at the same time do not retry at the same time.
+	Jitter float64
+	// RetryStatusCodes are the HTTP status codes on which the call is retried.
+	RetryStatusCodes []int
+	// RetryError decides whether the call failed with the given error is retried.
+	// If nil, IsTransientError is used.
+	RetryError func(err error) bool
+	// RetryWrites enables retrying requests that are not idempotent.
+	RetryWrites bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy making at most 4 attempts with a jittered
+// exponential backoff starting from 500 milliseconds.  Network errors and the HTTP
+// status codes 500, 502, 503 and 504 are retried.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		RetryStatusCodes: []int{
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// acceptedPolicy is the RetryPolicy by which GET requests answered with HTTP
+// status 202 are repeated if the Toon has no RetryPolicy.
+var acceptedPolicy = &RetryPolicy{
+	MaxAttempts:    10,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     time.Second,
+	Multiplier:     1.5,
+	Jitter:         0.2,
+}
+
+// IsTransientError reports whether err is a network error that is likely to
+// disappear when the call is retried, e.g. a timeout or a connection reset.
+func IsTransientError(err error) bool {
+
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// shouldRetry decides whether the request r should be retried after the given
+// attempt resulted in the response res or the error err.
+func (p *RetryPolicy) shouldRetry(r request, attempt int, res *http.Response, err error) bool {
+
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+
+	if r.write && !p.RetryWrites && !(r.login && err != nil) {
+		return false
+	}
+
+	if err != nil {
+		if p.RetryError != nil {
+			return p.RetryError(err)
+		}
+		return IsTransientError(err)
+	}
+
+	if res.StatusCode == http.StatusAccepted {
+		// an accepted write is being processed; it must not be repeated
+		return !r.write
+	}
+	for _, code := range p.RetryStatusCodes {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay after the given attempt, i.e. InitialBackoff multiplied
+// attempt-1 times by Multiplier, limited by MaxBackoff and reduced by a random
+// fraction of at most Jitter.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+
+	if p == nil {
+		return 0
+	}
+
+	m := p.Multiplier
+	if m < 1 {
+		m = 1
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(m, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d -= d * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+
+	return time.Duration(d)
+}
@@ -0,0 +1,231 @@
+package gotoon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+
+	p := &RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		d := p.backoff(attempt + 1)
+		if d > max || d < max/2 {
+			t.Errorf("attempt %d: backoff %s not in [%s, %s]", attempt+1, d, max/2, max)
+		}
+	}
+}
+
+func TestRetryPolicyRetriesGet(t *testing.T) {
+
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	toon := Toon{RetryPolicy: p}
+
+	res, err := toon.send(context.Background(), s.Client(), request{method: "GET", url: s.URL, auth: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || calls != 3 {
+		t.Errorf("expected 200 after 3 calls, got %d after %d calls", res.StatusCode, calls)
+	}
+}
+
+func TestRetryPolicySkipsWrites(t *testing.T) {
+
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer s.Close()
+
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	toon := Toon{RetryPolicy: p}
+
+	r := request{method: "POST", url: s.URL, auth: true, write: true}
+	res, err := toon.send(context.Background(), s.Client(), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+	if calls != 1 {
+		t.Errorf("write retried without RetryWrites: %d calls", calls)
+	}
+
+	calls = 0
+	p.RetryWrites = true
+	res, err = toon.send(context.Background(), s.Client(), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+	if int(calls) != p.MaxAttempts {
+		t.Errorf("expected %d attempts, got %d", p.MaxAttempts, calls)
+	}
+}
+
+func TestRetryPolicyRetriesLogin(t *testing.T) {
+
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer s.Close()
+
+	// the connection is reset once before the request reaches the server
+	var resets int32
+	reset := func(call *Call, next Invoker) (*http.Response, error) {
+		if atomic.AddInt32(&resets, 1) == 1 {
+			return nil, syscall.ECONNRESET
+		}
+		return next(call)
+	}
+
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	toon := Toon{RetryPolicy: p, Interceptors: []Interceptor{reset}}
+
+	// a login failed without a response is retried, but not a failed response
+	r := request{endpoint: EndpointToken, method: "POST", url: s.URL, write: true, login: true}
+	res, err := toon.send(context.Background(), s.Client(), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadGateway || calls != 1 || resets != 2 {
+		t.Errorf("expected 502 after a reset, got %d after %d calls and %d attempts", res.StatusCode, calls, resets)
+	}
+
+	// a write is not retried
+	calls, resets = 0, 0
+	r = request{method: "POST", url: s.URL, auth: true, write: true}
+	if _, err = toon.send(context.Background(), s.Client(), r); !errors.Is(err, syscall.ECONNRESET) || resets != 1 {
+		t.Errorf("write retried without RetryWrites: %+v after %d attempts", err, resets)
+	}
+}
+
+func TestRetryPolicyPollsAccepted(t *testing.T) {
+
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	// repeated without a RetryPolicy
+	toon := Toon{}
+	res, err := toon.send(context.Background(), s.Client(), request{method: "GET", url: s.URL, auth: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || calls != 3 {
+		t.Errorf("expected 200 after 3 calls, got %d after %d calls", res.StatusCode, calls)
+	}
+
+	// at most MaxAttempts times with a RetryPolicy
+	calls = 0
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer a.Close()
+
+	toon.RetryPolicy = &RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond}
+	res, err = toon.send(context.Background(), a.Client(), request{method: "GET", url: a.URL, auth: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted || calls != 4 {
+		t.Errorf("expected 202 after 4 calls, got %d after %d calls", res.StatusCode, calls)
+	}
+
+	// accepted writes are never repeated
+	calls = 0
+	toon.RetryPolicy.RetryWrites = true
+	res, err = toon.send(context.Background(), a.Client(), request{method: "POST", url: a.URL, auth: true, write: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted || calls != 1 {
+		t.Errorf("accepted write repeated: %d calls", calls)
+	}
+}
+
+func TestAcceptedPolicyBounded(t *testing.T) {
+	var total time.Duration
+	for attempt := 1; attempt < acceptedPolicy.MaxAttempts; attempt++ {
+		total += acceptedPolicy.backoff(attempt)
+	}
+	if acceptedPolicy.MaxAttempts <= 0 || total > 10*time.Second {
+		t.Errorf("unbounded polling of accepted requests: %d attempts in %s", acceptedPolicy.MaxAttempts, total)
+	}
+}
+
+func TestRetryPolicyRetriesTruncatedBody(t *testing.T) {
+
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// announce more than is sent
+			w.Header().Set("content-length", "100")
+			w.Write([]byte("cut"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	// the truncated response is one of the attempts
+	toon := Toon{RetryPolicy: &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}}
+	res, err := toon.send(context.Background(), s.Client(), request{method: "GET", url: s.URL, auth: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	b, _ := ioutil.ReadAll(res.Body)
+	if string(b) != "ok" || calls != 2 {
+		t.Errorf("expected ok after 2 calls, got %q after %d calls", b, calls)
+	}
+
+	calls = 0
+	toon.RetryPolicy.MaxAttempts = 1
+	if _, err := toon.send(context.Background(), s.Client(), request{method: "GET", url: s.URL, auth: true}); !errors.Is(err, io.ErrUnexpectedEOF) || calls != 1 {
+		t.Errorf("expected unexpected EOF after 1 call, got %v after %d calls", err, calls)
+	}
+}
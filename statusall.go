@@ -0,0 +1,67 @@
+package gotoon
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultConcurrency is the default maximum number of concurrent requests made
+// by GetStatusAll.
+const defaultConcurrency = 4
+
+// StatusResult holds the Status of an agreement retrieved by GetStatusAll, or
+// the error retrieving it.
+type StatusResult struct {
+	Agreement Agreement
+	Status    Status
+	Err       error
+}
+
+// GetStatusAll retrieves the Status of all agreements concurrently, making at most
+// Concurrency requests at the same time.  The results are keyed by AgreementID;
+// an agreement for which the status cannot be retrieved has the error in its
+// result, and doesn't fail the others.
+//
+// An error is returned only if the agreements cannot be retrieved.
+func (t *Toon) GetStatusAll(ctx context.Context) (results map[string]StatusResult, err error) {
+
+	agreements, err := t.getAgreements(ctx)
+	if err != nil {
+		return
+	}
+
+	n := t.Concurrency
+	if n <= 0 {
+		n = defaultConcurrency
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	results = make(map[string]StatusResult, len(agreements))
+	sem := make(chan struct{}, n)
+
+	for _, agreement := range agreements {
+		wg.Add(1)
+		go func(agreement Agreement) {
+			defer wg.Done()
+
+			r := StatusResult{Agreement: agreement}
+
+			select {
+			case sem <- struct{}{}:
+				r.Status, r.Err = t.getStatus(ctx, agreement)
+				<-sem
+			case <-ctx.Done():
+				r.Err = ctx.Err()
+			}
+
+			mu.Lock()
+			results[agreement.AgreementID] = r
+			mu.Unlock()
+		}(agreement)
+	}
+
+	wg.Wait()
+	return
+}
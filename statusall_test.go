@@ -0,0 +1,70 @@
+package gotoon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetStatusAll(t *testing.T) {
+
+	var active, maxActive int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+EndpointAgreements {
+			w.Write([]byte(`[{"agreementId": "1"}, {"agreementId": "2"}, {"agreementId": "3"}, {"agreementId": "bad"}]`))
+			return
+		}
+
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		if strings.HasPrefix(r.URL.Path, "/bad/") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"thermostatInfo": {"currentSetpoint": 2000}}`))
+	}))
+	defer s.Close()
+
+	toon := Toon{
+		Endpoints:   Endpoints{APIBaseURL: s.URL},
+		HTTPClient:  s.Client(),
+		Concurrency: 2,
+		accessToken: token{
+			AccessToken:           "access",
+			ExpiresAt:             time.Now().Add(time.Hour),
+			RefreshTokenExpiresAt: time.Now().Add(time.Hour),
+		},
+	}
+
+	results, err := toon.GetStatusAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if r := results[id]; r.Err != nil || r.Status.ThermostatInfo.CurrentSetPoint != 2000 {
+			t.Errorf("unexpected result of %s: %+v", id, r)
+		}
+	}
+	if results["bad"].Err == nil {
+		t.Errorf("error of agreement bad not reported")
+	}
+	if maxActive > 2 {
+		t.Errorf("more than 2 concurrent requests: %d", maxActive)
+	}
+}
@@ -0,0 +1,45 @@
+package gotoon
+
+import (
+	"time"
+)
+
+// DeviceTimeZone is the time zone of the Toon devices.
+const DeviceTimeZone = "Europe/Amsterdam"
+
+// DeviceLocation returns the location of the DeviceTimeZone, to be used as the
+// Location of the Toon.
+func DeviceLocation() (*time.Location, error) {
+	return time.LoadLocation(DeviceTimeZone)
+}
+
+// in presents the time in the location loc, if loc is not nil.
+func (t *Time) in(loc *time.Location) {
+	if loc != nil && !t.IsZero() {
+		t.Time = t.Time.In(loc)
+	}
+}
+
+// setLocation presents all times of the Status in the location loc.
+func (s *Status) setLocation(loc *time.Location) {
+	if loc == nil {
+		return
+	}
+	s.ThermostatStates.LastUpdatedFromDisplay.in(loc)
+	s.ThermostatInfo.LastUpdatedFromDisplay.in(loc)
+	s.PowerUsage.LastUpdatedFromDisplay.in(loc)
+	s.GasUsage.LastUpdatedFromDisplay.in(loc)
+	s.LastUpdateFromDisplay.in(loc)
+}
+
+// setLocation presents all times of the FlowData in the location loc.
+func (f *FlowData) setLocation(loc *time.Location) {
+	if loc == nil {
+		return
+	}
+	for _, points := range [][]FlowDataPoint{f.Hours, f.Days, f.Weeks, f.Months, f.Years} {
+		for i := range points {
+			points[i].Timestamp.in(loc)
+		}
+	}
+}
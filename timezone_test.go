@@ -0,0 +1,49 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTimeRoundTrip(t *testing.T) {
+
+	in := `{"thermostatStates":{"state":null,"lastUpdatedFromDisplay":1540000000123},` +
+		`"thermostatInfo":{"lastUpdatedFromDisplay":1540000000456},"lastUpdateFromDisplay":1540000000789}`
+
+	var status Status
+	if err := json.Unmarshal([]byte(in), &status); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if ms := status.ThermostatStates.LastUpdatedFromDisplay.UnixMilli(); ms != 1540000000123 {
+		t.Errorf("milliseconds lost: %d", ms)
+	}
+
+	out, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	var back Status
+	json.Unmarshal(out, &back)
+	if !back.LastUpdateFromDisplay.Equal(status.LastUpdateFromDisplay.Time) ||
+		!back.ThermostatInfo.LastUpdatedFromDisplay.Equal(status.ThermostatInfo.LastUpdatedFromDisplay.Time) {
+		t.Errorf("times not round-tripped: %s", out)
+	}
+}
+
+func TestFlowDataLocation(t *testing.T) {
+
+	loc, err := DeviceLocation()
+	if err != nil {
+		t.Skipf("time zone database not available: %+v", err)
+	}
+
+	// 2018-10-28 01:00 UTC is 02:00 CET, right after the DST change at 03:00 CEST
+	var flow FlowData
+	json.Unmarshal([]byte(`{"hours": [{"timestamp": 1540688400000, "unit": "m3", "value": 0.1}]}`), &flow)
+	flow.setLocation(loc)
+
+	ts := flow.Hours[0].Timestamp
+	if name, offset := ts.Zone(); name != "CET" || offset != 3600 || ts.Hour() != 2 {
+		t.Errorf("unexpected time in device zone: %s", ts)
+	}
+}
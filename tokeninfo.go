@@ -0,0 +1,101 @@
+package gotoon
+
+import (
+	"time"
+)
+
+// TokenInfo describes the current session with the Toon API, without revealing
+// the tokens themselves.
+type TokenInfo struct {
+	// HasToken indicates whether the Toon has an access token, i.e. it has logged in.
+	HasToken bool
+	// ExpiresAt is the time at which the access token expires.
+	ExpiresAt time.Time
+	// RefreshTokenExpiresAt is the time at which the refresh token expires, after
+	// which the Toon needs to login again.
+	RefreshTokenExpiresAt time.Time
+}
+
+// TokenInfo returns the information of the current access token.
+func (t *Toon) TokenInfo() TokenInfo {
+	mu := t.tokenLock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	return t.tokenInfo()
+}
+
+func (t *Toon) tokenInfo() TokenInfo {
+	return TokenInfo{
+		HasToken:              t.accessToken.AccessToken != "",
+		ExpiresAt:             t.accessToken.ExpiresAt,
+		RefreshTokenExpiresAt: t.accessToken.RefreshTokenExpiresAt,
+	}
+}
+
+// TokenEventType is the type of a TokenEvent.
+type TokenEventType int
+
+const (
+	// TokenLogin is emitted after a successful login.
+	TokenLogin TokenEventType = iota
+	// TokenRefresh is emitted after a successful refresh of the access token.
+	TokenRefresh
+	// TokenRefreshFailed is emitted when refreshing the access token fails.
+	TokenRefreshFailed
+	// TokenRefreshExpiring is emitted once when the refresh token is about to
+	// expire, see Toon.RefreshExpiryWarning.
+	TokenRefreshExpiring
+	// TokenRefreshExpired is emitted when the refresh token has expired, before
+	// logging in again.
+	TokenRefreshExpired
+)
+
+func (e TokenEventType) String() string {
+	switch e {
+	case TokenLogin:
+		return "login"
+	case TokenRefresh:
+		return "refresh"
+	case TokenRefreshFailed:
+		return "refresh failed"
+	case TokenRefreshExpiring:
+		return "refresh token expiring"
+	case TokenRefreshExpired:
+		return "refresh token expired"
+	default:
+		return "unknown"
+	}
+}
+
+// TokenEvent is passed to Toon.OnTokenEvent on changes of the access token.
+type TokenEvent struct {
+	Type TokenEventType
+	// Info is the information of the access token after the event.
+	Info TokenInfo
+	// Err is the error of a TokenRefreshFailed event.
+	Err error
+}
+
+// emit calls the OnTokenEvent callback of the Toon, if it is set.
+func (t *Toon) emit(typ TokenEventType, err error) {
+	if t.OnTokenEvent != nil {
+		t.OnTokenEvent(TokenEvent{Type: typ, Info: t.tokenInfo(), Err: err})
+	}
+}
+
+// checkRefreshExpiry emits a TokenRefreshExpiring event if the refresh token
+// expires within the RefreshExpiryWarning period, once per refresh token.
+func (t *Toon) checkRefreshExpiry() {
+
+	expiresAt := t.accessToken.RefreshTokenExpiresAt
+
+	if t.RefreshExpiryWarning <= 0 || expiresAt.Equal(t.expiryWarned) {
+		return
+	}
+
+	if expiresAt.Sub(t.now()) < t.RefreshExpiryWarning {
+		t.expiryWarned = expiresAt
+		t.emit(TokenRefreshExpiring, nil)
+	}
+}
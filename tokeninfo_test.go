@@ -0,0 +1,71 @@
+package gotoon
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenEvents(t *testing.T) {
+
+	fail := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"fault": "invalid refresh token"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "new", "expires_in": "3600", "refresh_token": "new", "refresh_token_expires_in": "7200"}`))
+	}))
+	defer s.Close()
+
+	var events []TokenEventType
+	var lastErr error
+	toon := Toon{
+		Endpoints:            Endpoints{TokenURL: s.URL},
+		HTTPClient:           s.Client(),
+		RefreshExpiryWarning: 90 * time.Minute,
+		OnTokenEvent: func(e TokenEvent) {
+			events = append(events, e.Type)
+			lastErr = e.Err
+		},
+		accessToken: token{
+			AccessToken:           "old",
+			RefreshToken:          "old",
+			ExpiresAt:             time.Now().Add(-time.Minute),
+			RefreshTokenExpiresAt: time.Now().Add(time.Hour),
+		},
+	}
+
+	// expired access token is refreshed; the refresh token is expiring
+	if !toon.hasValidToken(context.Background()) {
+		t.Fatalf("token not refreshed")
+	}
+	if info := toon.TokenInfo(); !info.HasToken || time.Until(info.ExpiresAt) < 50*time.Minute {
+		t.Errorf("unexpected token info: %+v", info)
+	}
+
+	// failing refresh is reported
+	fail = true
+	toon.accessToken.ExpiresAt = time.Now().Add(-time.Minute)
+	if toon.hasValidToken(context.Background()) {
+		t.Fatalf("token valid after failed refresh")
+	}
+	var apiErr *APIError
+	if !errors.As(lastErr, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected refresh error: %+v", lastErr)
+	}
+
+	// expired refresh token is reported
+	toon.accessToken.RefreshTokenExpiresAt = time.Now().Add(-time.Minute)
+	toon.hasValidToken(context.Background())
+
+	expected := []TokenEventType{TokenRefreshExpiring, TokenRefresh, TokenRefreshFailed, TokenRefreshExpired}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+}
@@ -0,0 +1,99 @@
+package gotoon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// TokenStore persists the access token of the Toon API between sessions.  The
+// token data is opaque to the store; it contains the access and refresh tokens
+// and thus must be stored securely.
+type TokenStore interface {
+	// LoadToken returns the stored token data, or nil if no token is stored.
+	LoadToken() ([]byte, error)
+	// SaveToken stores the token data, replacing the previously stored one.
+	SaveToken(data []byte) error
+}
+
+// FileTokenStore is a TokenStore keeping the token data in a file, readable
+// and writable only by the owner.
+type FileTokenStore string
+
+// LoadToken reads the token data from the file.  It returns nil if the file
+// doesn't exist.
+func (f FileTokenStore) LoadToken() ([]byte, error) {
+	data, err := ioutil.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// SaveToken writes the token data to the file, creating the file if needed.
+func (f FileTokenStore) SaveToken(data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(string(f)), filepath.Base(string(f))+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), string(f))
+}
+
+// storedToken is the token as stored in the TokenStore.  Unlike the token, its
+// JSON encoding includes the access and refresh tokens.
+type storedToken token
+
+// loadToken loads the access token from the TokenStore of the Toon.  It returns
+// false if there is no TokenStore or no stored token.
+func (t *Toon) loadToken(ctx context.Context) bool {
+	if t.TokenStore == nil {
+		return false
+	}
+
+	data, err := t.TokenStore.LoadToken()
+	if err != nil {
+		t.log(ctx, slog.LevelWarn, "fail loading token", slog.Any("error", err))
+		return false
+	}
+	if data == nil {
+		return false
+	}
+
+	var tk token
+	if err = json.Unmarshal(data, (*storedToken)(&tk)); err != nil {
+		t.log(ctx, slog.LevelWarn, "fail decoding stored token", slog.Any("error", err))
+		return false
+	}
+	t.accessToken = tk
+
+	return t.accessToken.AccessToken != ""
+}
+
+// saveToken saves the access token to the TokenStore of the Toon, if any.  A
+// failure is logged but doesn't fail the call, as the token is still usable.
+func (t *Toon) saveToken(ctx context.Context) {
+	if t.TokenStore == nil {
+		return
+	}
+
+	data, err := json.Marshal((*storedToken)(&t.accessToken))
+	if err == nil {
+		err = t.TokenStore.SaveToken(data)
+	}
+	if err != nil {
+		t.log(ctx, slog.LevelWarn, "fail saving token", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,34 @@
+package gotoon
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileTokenStore(t *testing.T) {
+
+	store := FileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+
+	toon := Toon{TokenStore: store}
+	if toon.hasValidToken(context.Background()) {
+		t.Fatalf("valid token without a stored token")
+	}
+
+	toon.accessToken = token{
+		AccessToken:           "access",
+		RefreshToken:          "refresh",
+		ExpiresAt:             time.Now().Add(time.Hour),
+		RefreshTokenExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	toon.saveToken(context.Background())
+
+	restored := Toon{TokenStore: store}
+	if !restored.hasValidToken(context.Background()) {
+		t.Fatalf("stored token not restored")
+	}
+	if restored.accessToken.AccessToken != "access" || restored.accessToken.RefreshToken != "refresh" {
+		t.Errorf("restored token mismatch: %+v", restored.accessToken)
+	}
+}
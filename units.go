@@ -0,0 +1,97 @@
+package gotoon
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Temperature is a temperature in hundredths of a degree Celsius, as used by the
+// Toon API, e.g. 2050 is 20.5°C.
+type Temperature int
+
+// TemperatureFromCelsius converts degrees Celsius into a Temperature.
+func TemperatureFromCelsius(c float64) Temperature {
+	return Temperature(math.Round(c * 100))
+}
+
+// Celsius returns the temperature in degrees Celsius.
+func (t Temperature) Celsius() float64 { return float64(t) / 100 }
+
+func (t Temperature) String() string { return fmt.Sprintf("%.2f°C", t.Celsius()) }
+
+// Energy is an amount of energy in Wh, as used by the Toon API.  It is a float, so
+// that averages such as PowerUsage.AvgDayValue keep their decimals.
+type Energy float64
+
+// EnergyFromKWh converts kWh into an Energy.
+func EnergyFromKWh(kwh float64) Energy { return Energy(kwh * 1000) }
+
+// KWh returns the energy in kWh.
+func (e Energy) KWh() float64 { return float64(e) / 1000 }
+
+func (e Energy) String() string { return fmt.Sprintf("%.3f kWh", e.KWh()) }
+
+// Volume is a volume of gas in dm³ (liters), as used by the Toon API.  It is a
+// float, so that small volumes such as those of a FlowDataPoint keep their decimals.
+type Volume float64
+
+// VolumeFromCubicMeters converts m³ into a Volume.
+func VolumeFromCubicMeters(m3 float64) Volume { return Volume(m3 * 1000) }
+
+// CubicMeters returns the volume in m³.
+func (v Volume) CubicMeters() float64 { return float64(v) / 1000 }
+
+func (v Volume) String() string { return fmt.Sprintf("%.3f m³", v.CubicMeters()) }
+
+// GasFlow is a flow of gas in dm³ (liters) per hour, as used by the Toon API.
+type GasFlow float64
+
+// GasFlowFromCubicMetersPerHour converts m³/h into a GasFlow.
+func GasFlowFromCubicMetersPerHour(m3h float64) GasFlow { return GasFlow(m3h * 1000) }
+
+// CubicMetersPerHour returns the flow in m³/h.
+func (f GasFlow) CubicMetersPerHour() float64 { return float64(f) / 1000 }
+
+func (f GasFlow) String() string { return fmt.Sprintf("%.3f m³/h", f.CubicMetersPerHour()) }
+
+// Power is a power in W, as used by the Toon API.  It is a float, so that averages
+// such as PowerUsage.AvgValue keep their decimals.
+type Power float64
+
+// PowerFromKilowatts converts kW into a Power.
+func PowerFromKilowatts(kw float64) Power { return Power(kw * 1000) }
+
+// Kilowatts returns the power in kW.
+func (p Power) Kilowatts() float64 { return float64(p) / 1000 }
+
+func (p Power) String() string { return strconv.FormatFloat(float64(p), 'f', -1, 64) + " W" }
+
+// Money is an amount of money in euros.
+type Money float64
+
+// MoneyFromCents converts euro cents into Money.
+func MoneyFromCents(cents int64) Money { return Money(cents) / 100 }
+
+// Euros returns the amount in euros.
+func (m Money) Euros() float64 { return float64(m) }
+
+// Cents returns the amount in euro cents, rounded to the nearest cent.
+func (m Money) Cents() int64 { return int64(math.Round(float64(m) * 100)) }
+
+func (m Money) String() string { return fmt.Sprintf("€%.2f", float64(m)) }
+
+// Volume returns the value of a gas consumption data point as a Volume, converted
+// according to the Unit of the data point.  It returns false if the unit is not a
+// unit of volume.
+func (p FlowDataPoint) Volume() (v Volume, ok bool) {
+	switch strings.ToLower(p.Unit) {
+	case "m3", "m³":
+		return VolumeFromCubicMeters(float64(p.Value)), true
+	case "dm3", "dm³", "l":
+		return Volume(p.Value), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,118 @@
+package gotoon
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestUnits(t *testing.T) {
+
+	var status Status
+	err := json.Unmarshal([]byte(`{
+		"thermostatInfo": {"currentSetpoint": 2050, "currentDisplayTemp": 1987},
+		"powerUsage": {"value": 432, "dayCost": 1.23, "meterReading": 1234567},
+		"gasUsage": {"meterReading": 4567890, "dayUsage": 3250, "dayCost": 2.5}
+	}`), &status)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	for _, c := range []struct{ got, expected string }{
+		{status.ThermostatInfo.CurrentSetPoint.String(), "20.50°C"},
+		{status.ThermostatInfo.CurrentDisplayTemp.String(), "19.87°C"},
+		{status.PowerUsage.Value.String(), "432 W"},
+		{status.PowerUsage.DayCost.String(), "€1.23"},
+		{status.PowerUsage.MeterReading.String(), "1234.567 kWh"},
+		{status.GasUsage.MeterReading.String(), "4567.890 m³"},
+		{status.GasUsage.DayUsage.String(), "3.250 m³"},
+	} {
+		if c.got != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, c.got)
+		}
+	}
+
+	if TemperatureFromCelsius(21.5) != 2150 || Temperature(2150).Celsius() != 21.5 {
+		t.Errorf("temperature conversion mismatch")
+	}
+	if status.GasUsage.DayCost.Cents() != 250 || MoneyFromCents(250) != 2.5 {
+		t.Errorf("money conversion mismatch")
+	}
+
+	if PowerFromKilowatts(1.5) != 1500 || Power(1500).Kilowatts() != 1.5 {
+		t.Errorf("power conversion mismatch")
+	}
+	if GasFlowFromCubicMetersPerHour(0.15) != 150 || GasFlow(150).String() != "0.150 m³/h" {
+		t.Errorf("gas flow conversion mismatch")
+	}
+}
+
+func TestFlowDataPointUnits(t *testing.T) {
+
+	var flow FlowData
+	err := json.Unmarshal([]byte(`{"hours": [
+		{"timestamp": 1553781600000, "unit": "m3", "value": 0.125},
+		{"timestamp": 1553781900000, "unit": "dm3", "value": "250"},
+		{"timestamp": 1553782200000, "unit": "m3", "value": 0.0004},
+		{"timestamp": 1553782500000, "unit": "kWh", "value": 1.5}
+	]}`), &flow)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	for i, expected := range []Volume{125, 250, 0.4} {
+		if v, ok := flow.Hours[i].Volume(); !ok || math.Abs(float64(v-expected)) > 1e-9 {
+			t.Errorf("unexpected volume of %+v: %v", flow.Hours[i], float64(v))
+		}
+	}
+
+	// a unit other than volume is kept as it is
+	if _, ok := flow.Hours[3].Volume(); ok || flow.Hours[3].Unit != "kWh" || flow.Hours[3].Value != 1.5 {
+		t.Errorf("unexpected data point: %+v", flow.Hours[3])
+	}
+}
+
+func TestUnitsKeepDecimals(t *testing.T) {
+
+	// the numbers of the corpus match the types of the models
+	var drifts []SchemaDrift
+	toon := Toon{OnSchemaDrift: func(e *SchemaDriftError) { drifts = append(drifts, e.Drifts...) }}
+
+	for _, c := range []struct {
+		file string
+		v    interface{}
+	}{
+		{"testdata/status.json", &Status{}},
+		{"testdata/flow.json", &FlowData{}},
+	} {
+		data, err := os.ReadFile(c.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := toon.decode(context.Background(), c.file, data, c.v); err != nil {
+			t.Errorf("%s: unexpected error: %+v", c.file, err)
+		}
+	}
+	for _, d := range drifts {
+		if d.Kind == DriftTypeMismatch {
+			t.Errorf("unexpected drift: %s", d)
+		}
+	}
+
+	var status Status
+	if err := json.Unmarshal([]byte(`{
+		"powerUsage": {"avgValue": 391.02, "avgDayValue": 9384.56},
+		"gasUsage": {"avgValue": 118.15, "avgDayValue": 2835.52}
+	}`), &status); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	b, _ := json.Marshal(status)
+	for _, v := range []string{"391.02", "9384.56", "118.15", "2835.52"} {
+		if !strings.Contains(string(b), v) {
+			t.Errorf("average %s not kept: %s", v, b)
+		}
+	}
+}
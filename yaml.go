@@ -0,0 +1,285 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a non-empty line of a YAML document without its comment.
+type yamlLine struct {
+	// num is the line number, starting from 1.
+	num int
+	// indent is the number of spaces before the text.
+	indent int
+	text   string
+}
+
+// unmarshalYAML decodes the YAML document data into v, by converting it into JSON
+// decoded with encoding/json, so that the json tags of v apply.
+//
+// Only the subset of YAML needed by a Config is supported: block mappings and
+// sequences, flow sequences of scalars, e.g. [a, b], plain and quoted scalars,
+// and comments.  Anchors, tags, flow mappings, multi-line scalars and multiple
+// documents are not.  Plain scalars looking like numbers, booleans or null are
+// decoded as such; strings like these need to be quoted.
+func unmarshalYAML(data []byte, v interface{}) error {
+
+	lines, err := yamlLines(string(data))
+	if err != nil {
+		return err
+	}
+
+	var tree interface{}
+	if len(lines) > 0 {
+		var next int
+		if tree, next, err = parseYAMLNode(lines, 0, lines[0].indent); err != nil {
+			return err
+		}
+		if next < len(lines) {
+			return fmt.Errorf("yaml: line %d: unexpected indentation", lines[next].num)
+		}
+	}
+
+	b, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// yamlLines splits the YAML document s into its non-empty lines, without comments.
+func yamlLines(s string) (lines []yamlLine, err error) {
+	for i, text := range strings.Split(s, "\n") {
+		text = strings.TrimRight(stripYAMLComment(text), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || (trimmed == "---" && len(lines) == 0) {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed in indentation", i+1)
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	return
+}
+
+// stripYAMLComment removes the comment, starting with a # at the start of the line
+// or after a space, from the line s.  A # in a quoted scalar is not a comment.
+func stripYAMLComment(s string) string {
+	var quote, prev byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && strings.IndexByte("\x00:-[,", prev) >= 0:
+			// a quote starting a scalar
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+		if c != ' ' && c != '\t' {
+			prev = c
+		}
+	}
+	return s
+}
+
+// isYAMLItem reports whether the text is an item of a block sequence.
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseYAMLNode parses the block mapping or sequence starting at lines[i] with the
+// given indentation, and returns it with the index of the line following it.
+func parseYAMLNode(lines []yamlLine, i, indent int) (interface{}, int, error) {
+	if isYAMLItem(lines[i].text) {
+		return parseYAMLSequence(lines, i, indent)
+	}
+	return parseYAMLMapping(lines, i, indent)
+}
+
+// parseYAMLMapping parses the block mapping starting at lines[i].
+func parseYAMLMapping(lines []yamlLine, i, indent int) (interface{}, int, error) {
+
+	m := make(map[string]interface{})
+
+	for i < len(lines) && lines[i].indent == indent && !isYAMLItem(lines[i].text) {
+		l := lines[i]
+
+		key, value, ok := splitYAMLKey(l.text)
+		if !ok {
+			return nil, i, fmt.Errorf("yaml: line %d: expected a key: %s", l.num, l.text)
+		}
+		k, err := parseYAMLScalar(key)
+		if err != nil {
+			return nil, i, fmt.Errorf("yaml: line %d: %w", l.num, err)
+		}
+		name := fmt.Sprint(k)
+		if _, dup := m[name]; dup {
+			return nil, i, fmt.Errorf("yaml: line %d: duplicate key: %s", l.num, name)
+		}
+		i++
+
+		switch {
+		case value != "":
+			if m[name], err = parseYAMLScalar(value); err != nil {
+				return nil, i, fmt.Errorf("yaml: line %d: %w", l.num, err)
+			}
+		case i < len(lines) && lines[i].indent > indent:
+			// a nested block
+			if m[name], i, err = parseYAMLNode(lines, i, lines[i].indent); err != nil {
+				return nil, i, err
+			}
+		case i < len(lines) && lines[i].indent == indent && isYAMLItem(lines[i].text):
+			// a sequence may have the indentation of its key
+			if m[name], i, err = parseYAMLSequence(lines, i, indent); err != nil {
+				return nil, i, err
+			}
+		default:
+			m[name] = nil
+		}
+	}
+
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, i, fmt.Errorf("yaml: line %d: unexpected indentation", lines[i].num)
+	}
+	return m, i, nil
+}
+
+// parseYAMLSequence parses the block sequence starting at lines[i].
+func parseYAMLSequence(lines []yamlLine, i, indent int) (interface{}, int, error) {
+
+	s := []interface{}{}
+
+	for i < len(lines) && lines[i].indent == indent && isYAMLItem(lines[i].text) {
+		l := lines[i]
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+
+		var (
+			v   interface{}
+			err error
+		)
+		switch _, _, isKey := splitYAMLKey(rest); {
+		case rest == "":
+			// the item is a nested block, or null
+			i++
+			if i < len(lines) && lines[i].indent > indent {
+				v, i, err = parseYAMLNode(lines, i, lines[i].indent)
+			}
+		case isKey || isYAMLItem(rest):
+			// the item is a block starting on the line of its dash
+			lines[i] = yamlLine{num: l.num, indent: l.indent + len(l.text) - len(rest), text: rest}
+			v, i, err = parseYAMLNode(lines, i, lines[i].indent)
+		default:
+			i++
+			if v, err = parseYAMLScalar(rest); err != nil {
+				err = fmt.Errorf("yaml: line %d: %w", l.num, err)
+			}
+		}
+		if err != nil {
+			return nil, i, err
+		}
+		s = append(s, v)
+	}
+
+	return s, i, nil
+}
+
+// splitYAMLKey splits the text of a mapping entry into its key and value.  The key
+// is followed by a colon and a space, or a colon at the end of the text.
+func splitYAMLKey(text string) (key, value string, ok bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLScalar parses the scalar or flow sequence s.
+func parseYAMLScalar(s string) (interface{}, error) {
+
+	switch {
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence: %s", s)
+		}
+		items := []interface{}{}
+		for _, item := range splitYAMLFlow(s[1 : len(s)-1]) {
+			v, err := parseYAMLScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+
+	case strings.HasPrefix(s, "{"), strings.HasPrefix(s, "&"), strings.HasPrefix(s, "*"),
+		strings.HasPrefix(s, "!"), strings.HasPrefix(s, "|"), strings.HasPrefix(s, ">"):
+		return nil, fmt.Errorf("unsupported YAML: %s", s)
+
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string: %s", s)
+		}
+		return v, nil
+
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("invalid quoted string: %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if c := s[0]; (c == '-' || c >= '0' && c <= '9') && json.Valid([]byte(s)) {
+		return json.Number(s), nil
+	}
+	return s, nil
+}
+
+// splitYAMLFlow splits the items of a flow sequence at the commas outside quotes.
+func splitYAMLFlow(s string) (items []string) {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(s[start:]))
+}
@@ -0,0 +1,76 @@
+package gotoon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalYAML(t *testing.T) {
+
+	var v interface{}
+	err := unmarshalYAML([]byte(`---
+# a comment
+name: 'O''Brien'   # a trailing comment
+url: "http://127.0.0.1/#fragment"
+tag: a#b
+rate: 2.5
+burst: 5
+on: true
+none: ~
+command: [helper, "--profile", 'home']
+args:
+- one
+- two
+nested:
+  items:
+    - key: a
+      value: 1
+    -
+      key: b
+  empty:
+`), &v)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := map[string]interface{}{
+		"name":    "O'Brien",
+		"url":     "http://127.0.0.1/#fragment",
+		"tag":     "a#b",
+		"rate":    2.5,
+		"burst":   5.0,
+		"on":      true,
+		"none":    nil,
+		"command": []interface{}{"helper", "--profile", "home"},
+		"args":    []interface{}{"one", "two"},
+		"nested": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"key": "a", "value": 1.0},
+				map[string]interface{}{"key": "b"},
+			},
+			"empty": nil,
+		},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %+v, got %+v", expected, v)
+	}
+}
+
+func TestUnmarshalYAMLErrors(t *testing.T) {
+
+	for _, doc := range []string{
+		"a: 1\n  b: 2\n",
+		"a:\n\tb: 2\n",
+		"a: 1\na: 2\n",
+		"just text\n",
+		"a: {b: 1}\n",
+		"a: &anchor 1\n",
+		"a: [1, 2\n",
+		"a: \"unterminated\n",
+	} {
+		var v interface{}
+		if err := unmarshalYAML([]byte(doc), &v); err == nil {
+			t.Errorf("expect error on %q, got %+v", doc, v)
+		}
+	}
+}